@@ -0,0 +1,77 @@
+// Command audit-verify checks the hash chain of an audit file written by
+// log.AuditSink.
+//
+//	audit-verify [-key-file FILE] [-last-hash HASH] AUDIT-FILE
+//
+// With -last-hash, the file must also still contain the line that had that
+// hash, which detects lines cut from the end of the file.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pjsaksa/go-utils/log"
+)
+
+func main() {
+	keyFile := flag.String("key-file", "", "file whose exact contents are the HMAC key")
+	lastHash := flag.String("last-hash", "", "previously recorded last hash")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: audit-verify [-key-file FILE] [-last-hash HASH] AUDIT-FILE")
+		os.Exit(2)
+	}
+
+	if err := verify(flag.Arg(0), *keyFile, *lastHash); err != nil {
+		fmt.Fprintln(os.Stderr, "audit-verify:", err)
+		os.Exit(1)
+	}
+}
+
+func verify(fileName, keyFile, lastHash string) error {
+	var key []byte
+	if len(keyFile) > 0 {
+		var err error
+		if key, err = os.ReadFile(keyFile); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	summary, err := log.VerifyAudit(file, key)
+	if err != nil {
+		return err
+	}
+
+	if len(lastHash) > 0 {
+		if err := findHash(fileName, lastHash); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("OK: %d records, last hash %s\n", summary.Records, summary.LastHash)
+	return nil
+}
+
+func findHash(fileName, hash string) error {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, hash+" ") {
+			return nil
+		}
+	}
+	return errors.New("last hash not found, the file was truncated")
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	go_http "net/http"
+	"strings"
+	"time"
+
+	"github.com/pjsaksa/go-utils/log"
+)
+
+// AccessLogFormat selects the form of the access log written by a Server.
+type AccessLogFormat int
+
+const (
+	// AccessLogNone disables the access log.
+	AccessLogNone AccessLogFormat = iota
+	// AccessLogCommon writes lines in the Common Log Format.
+	AccessLogCommon
+	// AccessLogCombined writes lines in the Combined Log Format: the Common
+	// Log Format followed by the referer and user agent.
+	AccessLogCombined
+	// AccessLogStructured writes a short line with everything, latency
+	// included, as record fields: remote, user, method, path, status, bytes,
+	// referer, user_agent and latency_us.
+	AccessLogStructured
+)
+
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+var accessLogger = logger.Named("access")
+
+// SetAccessLog makes the server log every request as INFO under the
+// component "http.access". The user is the one seen by the controller, after
+// any middleware. The access log is off by default; MessageSummary is called
+// either way.
+func (srv *Server) SetAccessLog(format AccessLogFormat) {
+	srv.accessLog = format
+}
+
+func (srv *Server) logAccess(req *go_http.Request, res Resolution, user User, start time.Time) {
+	if srv.accessLog == AccessLogNone || !accessLogger.Enabled(log.LevelInfo) {
+		return
+	}
+	latency := time.Since(start)
+	l := accessLogger.ForContext(req.Context())
+
+	remote := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	username := ""
+	if user != nil {
+		username = user.Username()
+	}
+
+	switch srv.accessLog {
+	case AccessLogCommon, AccessLogCombined:
+		line := fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s`,
+			clfField(remote),
+			clfField(username),
+			start.Format(clfTimeFormat),
+			clfEscape(req.Method),
+			clfEscape(req.RequestURI),
+			clfEscape(req.Proto),
+			res.StatusCode(),
+			clfBytes(res.Size()))
+		if srv.accessLog == AccessLogCombined {
+			line += fmt.Sprintf(` "%s" "%s"`,
+				clfField(req.Referer()),
+				clfField(req.UserAgent()))
+		}
+		l.INFO("%s", line)
+
+	case AccessLogStructured:
+		l.WithFields(
+			log.Field{Key: "remote", Value: remote},
+			log.Field{Key: "user", Value: username},
+			log.Field{Key: "method", Value: req.Method},
+			log.Field{Key: "path", Value: req.URL.Path},
+			log.Field{Key: "status", Value: res.StatusCode()},
+			log.Field{Key: "bytes", Value: res.Size()},
+			log.Field{Key: "referer", Value: req.Referer()},
+			log.Field{Key: "user_agent", Value: req.UserAgent()},
+			log.Field{Key: "latency_us", Value: latency.Microseconds()},
+		).INFO("%s %s %d", req.Method, req.URL.Path, res.StatusCode())
+	}
+}
+
+// clfField returns "-" for empty values, as the log formats require, and
+// escapes the others with clfEscape.
+func clfField(value string) string {
+	if len(value) == 0 {
+		return "-"
+	}
+	return clfEscape(value)
+}
+
+// clfEscape escapes a value the way Apache does, so that a client can't break
+// the quoting of a log line or forge another: quotes and backslashes are
+// escaped with a backslash, and control and non-ASCII bytes as \n, \t... or
+// \xhh.
+func clfEscape(value string) string {
+	idx := 0
+	for idx < len(value) && !clfNeedsEscape(value[idx]) {
+		idx++
+	}
+	if idx == len(value) {
+		return value
+	}
+
+	var b strings.Builder
+	b.WriteString(value[:idx])
+	for ; idx < len(value); idx++ {
+		c := value[idx]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\b':
+			b.WriteString(`\b`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c == '\v':
+			b.WriteString(`\v`)
+		case clfNeedsEscape(c):
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func clfNeedsEscape(c byte) bool {
+	return c < 0x20 || c >= 0x7f || c == '"' || c == '\\'
+}
+
+func clfBytes(size int64) string {
+	if size <= 0 {
+		return "-"
+	}
+	return fmt.Sprint(size)
+}
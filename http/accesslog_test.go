@@ -0,0 +1,122 @@
+package http
+
+import (
+	"encoding/json"
+	go_http "net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pjsaksa/go-utils/log"
+)
+
+func newAccessLogServer(format AccessLogFormat) *Server {
+	srv := NewServer(&testController{
+		handler: func(req *go_http.Request, urlParts []string, user User) Resolution {
+			return &ContentResolution{Content: []byte("hello")}
+		},
+	})
+	srv.Use(func(next RequestHandler) RequestHandler {
+		return func(req *go_http.Request, urlParts []string, user User) Resolution {
+			return next(req, urlParts, testUser("alice"))
+		}
+	})
+	srv.SetAccessLog(format)
+	return srv
+}
+
+func newAccessLogRequest() *go_http.Request {
+	req := httptest.NewRequest("GET", "/page?q=1", nil)
+	req.RemoteAddr = "192.0.2.1:4321"
+	req.Header.Set("Referer", "http://example.com/")
+	req.Header.Set("User-Agent", "test/1.0")
+	return req
+}
+
+func Test_AccessLogCombined(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	log.SetOutput(&out)
+	log.SetColor(false)
+
+	// Cleanup
+	defer func() {
+		log.ResetOutput()
+		log.ResetColor()
+	}()
+
+	// Test object
+	newAccessLogServer(AccessLogCombined).ServeHTTP(httptest.NewRecorder(), newAccessLogRequest())
+
+	// Verify output.
+	expected := regexp.MustCompile(`\[http\.access\] INFO: 192\.0\.2\.1 - alice \[\d\d/\w{3}/\d{4}:\d\d:\d\d:\d\d [-+]\d{4}\] ` +
+		`"GET /page\?q=1 HTTP/1\.1" 200 5 "http://example\.com/" "test/1\.0" trace_id=`)
+	if !expected.MatchString(out.String()) {
+		t.Errorf("Unexpected access log line:\n%s", out.String())
+	}
+}
+
+func Test_AccessLogStructured(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	log.SetOutput(&out)
+	log.SetFormat(log.FormatJSON)
+
+	// Cleanup
+	defer func() {
+		log.ResetOutput()
+		log.ResetFormat()
+	}()
+
+	// Test object
+	newAccessLogServer(AccessLogStructured).ServeHTTP(httptest.NewRecorder(), newAccessLogRequest())
+
+	// Verify output.
+	var record struct {
+		Message string
+		Fields  map[string]any
+	}
+	if err := json.Unmarshal([]byte(out.String()), &record); err != nil {
+		t.Fatalf("Invalid JSON %q: %s", out.String(), err)
+	}
+	if record.Message != "GET /page 200" {
+		t.Errorf("Unexpected message %q", record.Message)
+	}
+	for key, value := range map[string]any{
+		"remote":     "192.0.2.1",
+		"user":       "alice",
+		"status":     float64(200),
+		"bytes":      float64(5),
+		"referer":    "http://example.com/",
+		"user_agent": "test/1.0",
+	} {
+		if record.Fields[key] != value {
+			t.Errorf("Field %s: want %v, got %v", key, value, record.Fields[key])
+		}
+	}
+	if _, ok := record.Fields["latency_us"]; !ok {
+		t.Errorf("Latency missing: %s", out.String())
+	}
+}
+
+func Test_ClfField(t *testing.T) {
+	var data = []struct {
+		input string
+		want  string
+	}{
+		{"", "-"},
+		{"test/1.0", "test/1.0"},
+		{`evil" "forged`, `evil\" \"forged`},
+		{`back\slash`, `back\\slash`},
+		{"two\nlines\r\tand\x1b[31m", `two\nlines\r\tand\x1b[31m`},
+		{"caf\xc3\xa9", `caf\xc3\xa9`},
+	}
+
+	for _, d := range data {
+		got := clfField(d.input)
+		if got != d.want {
+			t.Errorf("FAIL: %q: want %q, got %q", d.input, d.want, got)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CertManager serves a TLS certificate from a certificate and a key file, and
+// reloads them when they change, or on SIGHUP. Connections already open keep
+// the certificate they were opened with.
+type CertManager struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+
+	// Watcher state
+	mutex    sync.Mutex
+	stamp    string
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewCertManager loads the certificate, and then checks the files for changes
+// every pollInterval (zero disables polling).
+func NewCertManager(certFile, keyFile string, pollInterval time.Duration) (*CertManager, error) {
+	m := &CertManager{
+		certFile: certFile,
+		keyFile:  keyFile,
+		done:     make(chan struct{}),
+	}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+
+	go m.watch(pollInterval)
+	return m, nil
+}
+
+// Reload loads the certificate from the files. On failure, the previous
+// certificate stays in use.
+func (m *CertManager) Reload() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stamp := m.fileStamp()
+	cert, err := tls.LoadX509KeyPair(m.certFile, m.keyFile)
+	if err != nil {
+		return fmt.Errorf("http.CertManager: %w", err)
+	}
+
+	m.cert.Store(&cert)
+	m.stamp = stamp
+	return nil
+}
+
+// GetCertificate is for tls.Config.GetCertificate.
+func (m *CertManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return m.cert.Load(), nil
+}
+
+// Close stops watching the files.
+func (m *CertManager) Close() {
+	m.stopOnce.Do(func() { close(m.done) })
+}
+
+// fileStamp identifies the current versions of the files.
+func (m *CertManager) fileStamp() string {
+	var stamp string
+	for _, name := range []string{m.certFile, m.keyFile} {
+		if info, err := os.Stat(name); err == nil {
+			stamp += fmt.Sprintf("%d.%d;", info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return stamp
+}
+
+func (m *CertManager) changed() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.fileStamp() != m.stamp
+}
+
+func (m *CertManager) watch(pollInterval time.Duration) {
+	var tick <-chan time.Time
+	if pollInterval > 0 {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	signals := make(chan os.Signal, 1)
+	notifyReload(signals)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-tick:
+			if !m.changed() {
+				continue
+			}
+		case <-signals:
+		case <-m.done:
+			return
+		}
+
+		if err := m.Reload(); err != nil {
+			logger.ERROR("%s", err)
+		} else {
+			logger.INFO("Reloaded TLS certificate %s", m.certFile)
+		}
+	}
+}
+
+// SetCertManager makes the server take its TLS certificate from m. StartTLS
+// and ServeTLS can then be called with empty file names.
+func (srv *Server) SetCertManager(m *CertManager) {
+	if srv.httpServer.TLSConfig == nil {
+		srv.httpServer.TLSConfig = &tls.Config{}
+	}
+	srv.httpServer.TLSConfig.GetCertificate = m.GetCertificate
+}
+
+// ------------------------------------------------------------
+
+const devCertLifetime = 365 * 24 * time.Hour
+
+// DevCertFiles returns a self-signed certificate for localhost, for
+// development. The certificate and its key are cached in dir, as
+// localhost.crt and localhost.key, and regenerated when less than a month of
+// validity is left.
+func DevCertFiles(dir string) (certFile, keyFile string, err error) {
+	certFile = filepath.Join(dir, "localhost.crt")
+	keyFile = filepath.Join(dir, "localhost.key")
+
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil &&
+			time.Until(leaf.NotAfter) > 30*24*time.Hour {
+			return certFile, keyFile, nil
+		}
+	}
+
+	certPEM, keyPEM, err := newDevCert()
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(certFile, certPEM, 0o644); err != nil {
+		return "", "", err
+	}
+
+	logger.WARNING("Generated self-signed development certificate %s", certFile)
+	return certFile, keyFile, nil
+}
+
+func newDevCert() (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(devCertLifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package http
+
+import "os"
+
+// notifyReload does nothing on systems without SIGHUP.
+func notifyReload(signals chan<- os.Signal) {}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_DevCertFiles(t *testing.T) {
+	// Setup
+	dir := t.TempDir()
+
+	// Test object
+	certFile, keyFile, err := DevCertFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := os.ReadFile(certFile)
+	if _, _, err := DevCertFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+	second, _ := os.ReadFile(certFile)
+
+	// Verify: the cached certificate is reused.
+	if len(first) == 0 || !bytes.Equal(first, second) {
+		t.Errorf("Certificate was not cached")
+	}
+	if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Key file is not private")
+	}
+}
+
+func Test_CertManagerReload(t *testing.T) {
+	// Setup
+	certFile, keyFile, err := DevCertFiles(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewCertManager(certFile, keyFile, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	before, _ := m.GetCertificate(nil)
+
+	// Test object: replace the files with a new certificate.
+	otherDir := t.TempDir()
+	newCert, newKey, err := DevCertFiles(otherDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pair := range [][2]string{{newKey, keyFile}, {newCert, certFile}} {
+		data, _ := os.ReadFile(pair[0])
+		if err := os.WriteFile(pair[1], data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Verify
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		after, _ := m.GetCertificate(nil)
+		if !bytes.Equal(after.Certificate[0], before.Certificate[0]) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Certificate was not reloaded")
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package http
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyReload relays the signal that asks for certificates to be reloaded.
+func notifyReload(signals chan<- os.Signal) {
+	signal.Notify(signals, syscall.SIGHUP)
+}
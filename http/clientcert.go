@@ -0,0 +1,78 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	go_http "net/http"
+	"os"
+)
+
+// ClientCertController is implemented by controllers that authenticate users
+// by TLS client certificate (see SetClientCAs).
+type ClientCertController interface {
+	// ClientCertUser maps a verified client certificate to a user, or returns
+	// nil if the certificate grants no access. The certificate's Subject,
+	// DNSNames, EmailAddresses and URIs, and CertFingerprint, identify it.
+	ClientCertUser(cert *x509.Certificate) User
+}
+
+// ClientCertMode tells whether clients must present a certificate.
+type ClientCertMode int
+
+const (
+	// ClientCertOptional verifies certificates that clients present; clients
+	// without one can still sign in with a password.
+	ClientCertOptional ClientCertMode = iota
+	// ClientCertRequired refuses TLS connections without a valid certificate.
+	ClientCertRequired
+)
+
+// SetClientCAs makes the server verify TLS client certificates against the
+// CA pool. The user of a request with a verified certificate comes from the
+// controller's ClientCertUser and takes precedence over a session cookie: the
+// cookie is then ignored, and signing out doesn't end its session. It must be
+// called before the server is started.
+func (srv *Server) SetClientCAs(pool *x509.CertPool, mode ClientCertMode) {
+	if srv.httpServer.TLSConfig == nil {
+		srv.httpServer.TLSConfig = &tls.Config{}
+	}
+	srv.httpServer.TLSConfig.ClientCAs = pool
+	switch mode {
+	case ClientCertRequired:
+		srv.httpServer.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		srv.httpServer.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+}
+
+// LoadCertPool reads a pool of CA certificates from a PEM file.
+func LoadCertPool(fileName string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("http.LoadCertPool: no certificates in " + fileName)
+	}
+	return pool, nil
+}
+
+// CertFingerprint returns the SHA-256 fingerprint of a certificate, in hex.
+func CertFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// clientCertUser returns the user of the request's verified client
+// certificate, if any.
+func (srv *Server) clientCertUser(req *go_http.Request) User {
+	ctrl, ok := srv.ctrl.(ClientCertController)
+	if !ok || req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	return ctrl.ClientCertUser(req.TLS.VerifiedChains[0][0])
+}
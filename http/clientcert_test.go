@@ -0,0 +1,172 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	go_http "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type certController struct {
+	testController
+}
+
+func (ctrl *certController) ClientCertUser(cert *x509.Certificate) User {
+	if cert.Subject.CommonName == "service-a" {
+		return testUser("service-a")
+	}
+	return nil
+}
+
+// newTestCert creates a certificate signed by parent, or a self-signed CA if
+// parent is nil.
+func newTestCert(t *testing.T, name string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := template, any(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func Test_ClientCertUser(t *testing.T) {
+	// Setup
+	ca := newTestCert(t, "test CA", nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+
+	srv := NewServer(&certController{
+		testController{
+			handler: func(req *go_http.Request, urlParts []string, user User) Resolution {
+				RequireUser(user)
+				return &ContentResolution{Content: []byte(user.Username())}
+			},
+		},
+	})
+	srv.SetClientCAs(pool, ClientCertOptional)
+
+	certFile, keyFile, err := DevCertFiles(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.ServeTLS(listener, certFile, keyFile)
+	defer srv.Shutdown(context.Background())
+
+	get := func(clientCert *tls.Certificate) (int, string) {
+		tlsConfig := &tls.Config{InsecureSkipVerify: true}
+		if clientCert != nil {
+			tlsConfig.Certificates = []tls.Certificate{*clientCert}
+		}
+		client := go_http.Client{
+			Timeout:   5 * time.Second,
+			Transport: &go_http.Transport{TLSClientConfig: tlsConfig},
+		}
+		resp, err := client.Get("https://" + listener.Addr().String() + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	// Test object
+	known := newTestCert(t, "service-a", &ca)
+	unknown := newTestCert(t, "service-b", &ca)
+	var data = []struct {
+		cert   *tls.Certificate
+		status int
+		body   string
+	}{
+		{&known, 200, "service-a"},
+		{&unknown, 403, "Forbidden\n"},
+		{nil, 403, "Forbidden\n"},
+	}
+
+	for _, d := range data {
+		status, body := get(d.cert)
+		if status != d.status || body != d.body {
+			t.Errorf("FAIL: want %d %q, got %d %q", d.status, d.body, status, body)
+		}
+	}
+}
+
+func Test_ClientCertSession(t *testing.T) {
+	// Setup
+	ca := newTestCert(t, "test CA", nil)
+	known := newTestCert(t, "service-a", &ca)
+
+	srv := NewServer(&certController{
+		testController{
+			handler: func(req *go_http.Request, urlParts []string, user User) Resolution {
+				RequireUser(user)
+				return &ContentResolution{Content: []byte(user.Username())}
+			},
+		},
+	})
+	srv.sessions.Put("alice-token", &Session{User: testUser("alice"), RefreshTime: time.Now()})
+
+	request := func(method, target, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{known.Leaf}}}
+		req.AddCookie(&go_http.Cookie{Name: "session", Value: cookie})
+		out := httptest.NewRecorder()
+		srv.ServeHTTP(out, req)
+		return out
+	}
+
+	// Test object
+	page := request("GET", "/", "alice-token")
+	unknown := request("GET", "/", "unknown-token")
+	signOut := request("POST", "/u/sign-out", "alice-token")
+
+	// Verify: the certificate user is served, and the cookie sessions are
+	// neither used nor ended.
+	if body := page.Body.String(); page.Code != 200 || body != "service-a" {
+		t.Errorf("FAIL: cookie of another user: got %d %q", page.Code, body)
+	}
+	if body := unknown.Body.String(); unknown.Code != 200 || body != "service-a" {
+		t.Errorf("FAIL: unknown cookie: got %d %q", unknown.Code, body)
+	}
+	if signOut.Code != go_http.StatusSeeOther {
+		t.Errorf("FAIL: sign-out: got %d", signOut.Code)
+	}
+	if session, _ := srv.sessions.Get("alice-token"); session == nil {
+		t.Errorf("Sign-out with a certificate ended the session of another user")
+	}
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	go_http "net/http"
+	"time"
+
+	"github.com/pjsaksa/go-utils/log"
+)
+
+// BaseController implements every ServerController method but HandleRequest,
+// with sensible defaults. Embed it in a controller:
+//
+//	type controller struct {
+//		http.BaseController
+//	}
+//
+//	ctrl := &controller{
+//		BaseController: http.NewBaseController(http.WithBindAddress(":8080")),
+//	}
+//
+// Sessions are kept in memory only, and are lost when the program exits,
+// unless WithSessionStore is given. Without WithLogin, or with WithoutLogin,
+// no one can sign in.
+type BaseController struct {
+	bindAddress string
+	cookieName  string
+	maxAge      time.Duration
+	login       func(user, password string) User
+	configure   func(*go_http.Server)
+	store       SessionStore
+}
+
+// ControllerOption configures a BaseController.
+type ControllerOption func(*BaseController)
+
+const (
+	defaultBindAddress = ":8080"
+	defaultCookieName  = "session"
+	defaultMaxAge      = 30 * 24 * time.Hour
+)
+
+func NewBaseController(options ...ControllerOption) BaseController {
+	ctrl := BaseController{
+		bindAddress: defaultBindAddress,
+		cookieName:  defaultCookieName,
+		maxAge:      defaultMaxAge,
+	}
+	for _, option := range options {
+		option(&ctrl)
+	}
+	return ctrl
+}
+
+// WithBindAddress sets the address to listen at. The default is ":8080".
+func WithBindAddress(address string) ControllerOption {
+	return func(ctrl *BaseController) { ctrl.bindAddress = address }
+}
+
+// WithCookieName sets the name of the session cookie. The default is
+// "session".
+func WithCookieName(name string) ControllerOption {
+	return func(ctrl *BaseController) { ctrl.cookieName = name }
+}
+
+// WithSessionMaxAge sets how long an unused session stays valid. The default
+// is 30 days.
+func WithSessionMaxAge(maxAge time.Duration) ControllerOption {
+	return func(ctrl *BaseController) { ctrl.maxAge = maxAge }
+}
+
+// WithLogin sets the function that checks sign-in credentials, returning nil
+// if they are not valid.
+func WithLogin(login func(user, password string) User) ControllerOption {
+	return func(ctrl *BaseController) { ctrl.login = login }
+}
+
+// WithoutLogin makes every sign-in fail, for sites without users or with
+// client certificates only. It undoes an earlier WithLogin.
+func WithoutLogin() ControllerOption {
+	return func(ctrl *BaseController) { ctrl.login = nil }
+}
+
+// WithHttpServerConfig sets a function to adjust the underlying http.Server,
+// e.g. its timeouts.
+func WithHttpServerConfig(configure func(*go_http.Server)) ControllerOption {
+	return func(ctrl *BaseController) { ctrl.configure = configure }
+}
+
+// WithSessionStore sets where sessions are kept.
+func WithSessionStore(store SessionStore) ControllerOption {
+	return func(ctrl *BaseController) { ctrl.store = store }
+}
+
+// WithMemorySessions keeps sessions in a MemorySessionStore, so they are lost
+// when the program exits. It undoes an earlier WithSessionStore.
+func WithMemorySessions() ControllerOption {
+	return func(ctrl *BaseController) { ctrl.store = NewMemorySessionStore() }
+}
+
+// ------------------------------------------------------------
+
+func (ctrl *BaseController) BindAddress() string {
+	if len(ctrl.bindAddress) == 0 {
+		return defaultBindAddress
+	}
+	return ctrl.bindAddress
+}
+
+func (ctrl *BaseController) SessionCookieName() string {
+	if len(ctrl.cookieName) == 0 {
+		return defaultCookieName
+	}
+	return ctrl.cookieName
+}
+
+func (ctrl *BaseController) SessionMaxAge() time.Duration {
+	if ctrl.maxAge <= 0 {
+		return defaultMaxAge
+	}
+	return ctrl.maxAge
+}
+
+func (ctrl *BaseController) ConfigureHttpServer(srv *go_http.Server) {
+	if ctrl.configure != nil {
+		ctrl.configure(srv)
+	}
+}
+
+// MessageSummary logs the request and its resolution as a chain, under the
+// component "http".
+func (ctrl *BaseController) MessageSummary(req *go_http.Request, res Resolution) {
+	log.LOG(logger.ForContext(req.Context()).DebugMsg("%s %s", req.Method, req.URL.Path), res.LogMessage())
+}
+
+func (ctrl *BaseController) Login(user, password string) User {
+	if ctrl.login == nil {
+		return nil
+	}
+	return ctrl.login(user, password)
+}
+
+// SessionStore returns the store of WithSessionStore, or nil if there is
+// none, which makes the server use LoadSessions and RefreshSession instead.
+func (ctrl *BaseController) SessionStore() SessionStore {
+	return ctrl.store
+}
+
+func (ctrl *BaseController) LoadSessions(SessionMap)           {}
+func (ctrl *BaseController) RefreshSession(string, SessionMap) {}
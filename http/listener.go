@@ -0,0 +1,198 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	go_http "net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Listen opens a listener for a bind address (ServerController.BindAddress),
+// which takes one of the forms:
+//
+//	host:port       a TCP address, as in net.Listen
+//	unix:/path      a Unix domain socket, created with socketMode
+//	systemd         the first socket passed by systemd socket activation
+//	systemd:name    the socket passed by systemd with FileDescriptorName=name
+func Listen(address string, socketMode fs.FileMode) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(address, "unix:"):
+		return ListenUnix(strings.TrimPrefix(address, "unix:"), socketMode)
+	case address == "systemd":
+		return SystemdListener("")
+	case strings.HasPrefix(address, "systemd:"):
+		return SystemdListener(strings.TrimPrefix(address, "systemd:"))
+	case len(address) == 0:
+		return net.Listen("tcp", ":http")
+	default:
+		return net.Listen("tcp", address)
+	}
+}
+
+// DefaultSocketMode is the permission of Unix domain sockets, unless the
+// server is given another one with SetSocketMode.
+const DefaultSocketMode fs.FileMode = 0o660
+
+// umaskLock keeps concurrent ListenUnix calls from restoring each other's
+// umask.
+var umaskLock sync.Mutex
+
+// ListenUnix listens on a Unix domain socket with the given permissions. A
+// stale socket left behind by an earlier process is removed first, but a
+// socket still accepting connections, or any other kind of file, is left alone
+// and reported as an error. The socket is removed when the listener is closed.
+//
+// The socket is created under a umask that grants no more than mode, so it is
+// never accessible beyond mode, not even for a moment. The umask is process
+// wide: files created by other goroutines meanwhile get the narrower umask too.
+func ListenUnix(path string, mode fs.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("http.ListenUnix: %s exists and is not a socket", path)
+		}
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("http.ListenUnix: %s is in use by another process", path)
+		}
+		if !errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, fmt.Errorf("http.ListenUnix: %s: %w", path, err)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	umaskLock.Lock()
+	restore := restrictUmask(mode)
+	listener, err := net.Listen("unix", path)
+	restore()
+	umaskLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// The umask may have narrowed the permissions below mode.
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// ------------------------------------------------------------
+
+// listenFdsStart is the first file descriptor passed by systemd.
+const listenFdsStart = 3
+
+var systemd struct {
+	sync.Mutex
+	parsed bool
+	err    error
+	names  []string
+	files  []*os.File
+}
+
+// SystemdListener returns a socket passed by systemd socket activation
+// (LISTEN_FDS): the one named name with FileDescriptorName=, or the first
+// one if name is empty. Each socket can be taken only once.
+//
+// The LISTEN_* environment variables are unset on the first call, so that
+// child processes don't mistake the sockets for their own.
+func SystemdListener(name string) (net.Listener, error) {
+	systemd.Lock()
+	defer systemd.Unlock()
+
+	if !systemd.parsed {
+		systemd.names, systemd.files, systemd.err = systemdFiles()
+		systemd.parsed = true
+	}
+	if systemd.err != nil {
+		return nil, systemd.err
+	}
+
+	for idx, file := range systemd.files {
+		if file == nil || (len(name) > 0 && systemd.names[idx] != name) {
+			continue
+		}
+		systemd.files[idx] = nil
+
+		// FileListener duplicates the descriptor, so the original is closed.
+		listener, err := net.FileListener(file)
+		file.Close()
+		return listener, err
+	}
+
+	if len(name) > 0 {
+		return nil, fmt.Errorf("http.SystemdListener: no socket named '%s'", name)
+	}
+	return nil, errors.New("http.SystemdListener: no sockets left")
+}
+
+func systemdFiles() ([]string, []*os.File, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil, errors.New("http.SystemdListener: no sockets passed by systemd")
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, nil, errors.New("http.SystemdListener: no sockets passed by systemd")
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	files := make([]*os.File, count)
+	for idx := range files {
+		if idx >= len(names) {
+			names = append(names, "")
+		}
+		files[idx] = os.NewFile(uintptr(listenFdsStart+idx), names[idx])
+	}
+	return names, files, nil
+}
+
+// ------------------------------------------------------------
+
+// SetSocketMode sets the permissions of the Unix domain socket created for a
+// "unix:" bind address. It must be called before the server is started.
+func (srv *Server) SetSocketMode(mode fs.FileMode) {
+	srv.socketMode = mode
+}
+
+// Serve serves HTTP on listener until the server is shut down, and then
+// returns nil.
+func (srv *Server) Serve(listener net.Listener) error {
+	logger.INFO("Listening HTTP at %s", listenerAddress(listener))
+	if err := srv.httpServer.Serve(listener); err != go_http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// ServeTLS is like Serve, for HTTPS.
+func (srv *Server) ServeTLS(listener net.Listener, certFile, keyFile string) error {
+	logger.INFO("Listening HTTPS at %s", listenerAddress(listener))
+	if err := srv.httpServer.ServeTLS(listener, certFile, keyFile); err != go_http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func listenerAddress(listener net.Listener) string {
+	addr := listener.Addr()
+	if addr.Network() == "unix" {
+		return "unix:" + addr.String()
+	}
+	return addr.String()
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package http
+
+import "io/fs"
+
+// restrictUmask does nothing on systems without umask.
+func restrictUmask(mode fs.FileMode) (restore func()) {
+	return func() {}
+}
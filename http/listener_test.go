@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net"
+	go_http "net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_ListenUnix(t *testing.T) {
+	// Setup
+	socketPath := filepath.Join(t.TempDir(), "http.sock")
+	if err := os.WriteFile(socketPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen("unix:"+socketPath, 0o600); err == nil {
+		t.Errorf("A regular file was replaced by a socket")
+	}
+	os.Remove(socketPath)
+
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socketPath, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	srv := NewServer(&testController{
+		handler: func(req *go_http.Request, urlParts []string, user User) Resolution {
+			return &ContentResolution{Content: []byte("over unix")}
+		},
+	})
+
+	// Test object
+	listener, err := Listen("unix:"+socketPath, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(listener)
+	defer srv.Shutdown(context.Background())
+
+	// Verify
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Type() != fs.ModeSocket || info.Mode().Perm() != 0o600 {
+		t.Errorf("Unexpected socket mode %s", info.Mode())
+	}
+	if _, err := Listen("unix:"+socketPath, 0o600); err == nil {
+		t.Errorf("A socket in use was replaced")
+	}
+
+	client := go_http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &go_http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	resp, err := client.Get("http://localhost/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "over unix" {
+		t.Errorf("Unexpected response %q", body)
+	}
+}
+
+func Test_SystemdListenerMissing(t *testing.T) {
+	// Setup
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+
+	// Test object
+	_, fds, err := systemdFiles()
+
+	// Verify: the sockets were passed to another process.
+	if err == nil || fds != nil {
+		t.Errorf("Sockets of another process were accepted")
+	}
+	for _, name := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		if _, ok := os.LookupEnv(name); ok {
+			t.Errorf("%s was left in the environment", name)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package http
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// restrictUmask narrows the umask so that files are created with no more
+// permissions than mode, and returns a function restoring it. The umask is
+// set to 0777 first, so that files created meanwhile by other goroutines are
+// never given more permissions than the umask allows.
+func restrictUmask(mode fs.FileMode) (restore func()) {
+	old := syscall.Umask(0o777)
+	syscall.Umask(old | int(^mode&fs.ModePerm))
+	return func() { syscall.Umask(old) }
+}
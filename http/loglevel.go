@@ -0,0 +1,90 @@
+package http
+
+import (
+	"fmt"
+	go_http "net/http"
+	"sort"
+	"strings"
+
+	"github.com/pjsaksa/go-utils/log"
+)
+
+// ServeLogLevel shows (GET) or changes (POST) the log level thresholds. Only
+// users for whom isAdmin returns true are allowed in.
+//
+// A POST takes the form values "level" (a level name, or "inherit" to make a
+// component follow its parent again) and optionally "component"; without a
+// component the global threshold is changed. An unknown component is a bad
+// request.
+func ServeLogLevel(req *go_http.Request, user User, isAdmin func(User) bool) Resolution {
+	RequireUser(user)
+	if !isAdmin(user) {
+		return &ErrorResolution{Status: go_http.StatusForbidden}
+	}
+
+	switch req.Method {
+	case "GET":
+		return &ContentResolution{
+			ContentType: "text/plain; charset=utf-8",
+			Content:     []byte(logLevelsText()),
+		}
+	case "POST":
+		return changeLogLevel(req, user)
+	default:
+		return &MethodNotAllowedResolution{Allowed: "GET, POST"}
+	}
+}
+
+func changeLogLevel(req *go_http.Request, user User) Resolution {
+	component := req.PostFormValue("component")
+	levelName := req.PostFormValue("level")
+	origin := fmt.Sprintf("user '%s'", user.Username())
+
+	if len(component) > 0 && !isLogComponent(component) {
+		return &ErrorResolution{
+			Status:  go_http.StatusBadRequest,
+			Message: fmt.Sprintf("Unknown component '%s'", component),
+		}
+	}
+
+	if levelName == "inherit" {
+		if len(component) == 0 {
+			return &ErrorResolution{
+				Status:  go_http.StatusBadRequest,
+				Message: "The global level cannot be inherited",
+			}
+		}
+		log.InheritLevel(component, origin)
+	} else {
+		level, err := log.ParseLevel(levelName)
+		if err != nil {
+			return &ErrorResolution{
+				Status:  go_http.StatusBadRequest,
+				Message: err.Error(),
+			}
+		}
+		log.ChangeLevel(component, level, origin)
+	}
+
+	return &ContentResolution{
+		ContentType: "text/plain; charset=utf-8",
+		Content:     []byte(logLevelsText()),
+	}
+}
+
+// isLogComponent tells if component is one of log.Components(). Changing the
+// level of any other name would create a logger that nothing uses.
+func isLogComponent(component string) bool {
+	components := log.Components()
+	idx := sort.SearchStrings(components, component)
+	return idx < len(components) && components[idx] == component
+}
+
+func logLevelsText() string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "global %s\n", log.GetLevel())
+	for _, name := range log.Components() {
+		fmt.Fprintf(&out, "%s %s\n", name, log.Named(name).Level())
+	}
+	return out.String()
+}
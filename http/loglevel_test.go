@@ -0,0 +1,56 @@
+package http
+
+import (
+	"io"
+	go_http "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pjsaksa/go-utils/log"
+)
+
+func Test_ServeLogLevel(t *testing.T) {
+	// Setup
+	log.SetOutput(io.Discard)
+	component := log.Named("loglevel")
+	isAdmin := func(User) bool { return true }
+
+	// Cleanup
+	defer func() {
+		log.ResetOutput()
+		component.ResetLevel()
+	}()
+
+	var data = []struct {
+		component string
+		status    int
+	}{
+		{"loglevel", go_http.StatusOK},
+		{"nosuch", go_http.StatusBadRequest},
+	}
+
+	for _, d := range data {
+		form := "component=" + d.component + "&level=WARNING"
+		req := httptest.NewRequest("POST", "/loglevel", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		status := go_http.StatusOK
+		if res, ok := ServeLogLevel(req, testUser("admin"), isAdmin).(*ErrorResolution); ok {
+			status = res.Status
+		}
+		if status != d.status {
+			t.Errorf("FAIL: %s: want status %d, got %d", d.component, d.status, status)
+		}
+	}
+
+	// Verify output.
+	if component.Level() != log.LevelWarning {
+		t.Errorf("FAIL: level of %s is %s", component.Name(), component.Level())
+	}
+	for _, name := range log.Components() {
+		if name == "nosuch" {
+			t.Errorf("FAIL: unknown component was created")
+		}
+	}
+}
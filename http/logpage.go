@@ -0,0 +1,25 @@
+package http
+
+import (
+	"html"
+
+	"github.com/pjsaksa/go-utils/log"
+)
+
+// LogPage renders colored log output, such as FormatText output captured from
+// a log.WriterSink, as a standalone HTML page.
+func LogPage(title string, text []byte) Resolution {
+	content := make([]byte, 0, len(text)*2)
+	content = append(content, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>"...)
+	content = append(content, html.EscapeString(title)...)
+	content = append(content, "</title>\n<style>\npre.log { background-color: #000; color: #e5e5e5; }\n"...)
+	content = append(content, log.HTMLStyle...)
+	content = append(content, "</style></head>\n<body><pre class=\"log\">"...)
+	content = log.AppendHTML(content, text)
+	content = append(content, "</pre></body></html>\n"...)
+
+	return &ContentResolution{
+		ContentType: "text/html; charset=utf-8",
+		Content:     content,
+	}
+}
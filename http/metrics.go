@@ -0,0 +1,60 @@
+package http
+
+import (
+	"fmt"
+	go_http "net/http"
+	"sort"
+	"strings"
+
+	"github.com/pjsaksa/go-utils/log"
+)
+
+// ServeLogMetrics returns the log package's message counters in Prometheus
+// text exposition format.
+func ServeLogMetrics(req *go_http.Request) Resolution {
+	if req.Method != "GET" {
+		return &MethodNotAllowedResolution{Allowed: "GET"}
+	}
+
+	return &ContentResolution{
+		ContentType: "text/plain; version=0.0.4; charset=utf-8",
+		Content:     []byte(logMetricsText(log.Counts())),
+	}
+}
+
+func logMetricsText(counts map[log.CounterKey]uint64) string {
+	keys := make([]log.CounterKey, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Component != keys[j].Component {
+			return keys[i].Component < keys[j].Component
+		}
+		return keys[i].Level < keys[j].Level
+	})
+
+	var out strings.Builder
+	out.WriteString("# HELP log_messages_total Number of log messages emitted.\n")
+	out.WriteString("# TYPE log_messages_total counter\n")
+	for _, key := range keys {
+		level := strings.ToLower(key.Level.String())
+		if len(key.Component) > 0 {
+			fmt.Fprintf(&out, "log_messages_total{component=\"%s\",level=\"%s\"} %d\n",
+				escapeLabelValue(key.Component),
+				level,
+				counts[key])
+		} else {
+			fmt.Fprintf(&out, "log_messages_total{level=\"%s\"} %d\n",
+				level,
+				counts[key])
+		}
+	}
+	return out.String()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/pjsaksa/go-utils/log"
+)
+
+func Test_LogMetricsText(t *testing.T) {
+	counts := map[log.CounterKey]uint64{
+		{Level: log.LevelError}:                      3,
+		{Level: log.LevelDebug}:                      10,
+		{Level: log.LevelWarning, Component: `a"b`}:  1,
+		{Level: log.LevelWarning, Component: "http"}: 2,
+		{Level: log.LevelInfo, Component: "http"}:    5,
+	}
+
+	const expected = "# HELP log_messages_total Number of log messages emitted.\n" +
+		"# TYPE log_messages_total counter\n" +
+		"log_messages_total{level=\"debug\"} 10\n" +
+		"log_messages_total{level=\"error\"} 3\n" +
+		"log_messages_total{component=\"a\\\"b\",level=\"warning\"} 1\n" +
+		"log_messages_total{component=\"http\",level=\"info\"} 5\n" +
+		"log_messages_total{component=\"http\",level=\"warning\"} 2\n"
+
+	if output := logMetricsText(counts); output != expected {
+		t.Errorf("FAIL:\nWANT:\n%s\nGOT:\n%s", expected, output)
+	}
+}
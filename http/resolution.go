@@ -123,7 +123,7 @@ func ServeFile(req *go_http.Request, fileName string, contentType string, maxAge
 	case err != nil:
 		return &ErrorResolution{
 			Status:  go_http.StatusInternalServerError,
-			Message: fmt.Sprintf("http.ServeFile: os.Open: ", err.Error()),
+			Message: fmt.Sprintf("http.ServeFile: os.Open: %s", err.Error()),
 		}
 	}
 
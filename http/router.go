@@ -0,0 +1,343 @@
+package http
+
+import (
+	"fmt"
+	go_http "net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RouteHandler handles a request matched by a Router.
+type RouteHandler func(req *go_http.Request, params Params, user User) Resolution
+
+// Router dispatches requests to handlers by URL pattern. A pattern consists
+// of "/"-separated segments, each one of:
+//
+//	projects     static text, matched as is
+//	:id          a parameter, matching any segment
+//	:id<int>     a typed parameter, matching only segments valid for the type
+//	*path        a catch-all, matching the rest of the path (last segment only)
+//
+// Built-in parameter types are int, uint, hex and uuid; ParamType adds more.
+//
+// When several routes match, the one with the more specific segment wins, the
+// segments compared from left to right: static text first, then typed
+// parameters, parameters, and catch-alls. Equally specific routes are tried in
+// registration order.
+//
+// Routes are registered for one HTTP method (GET, POST...) or, with Handle,
+// for all of them. A path matched only by routes of other methods is answered
+// with 405 Method Not Allowed, or for OPTIONS with the Allow header alone.
+// HEAD requests are served by GET routes, unless HEAD routes are registered.
+//
+// Router.HandleRequest has the signature of ServerController.HandleRequest.
+type Router struct {
+	routes     []*route
+	paramTypes map[string]func(string) bool
+}
+
+func NewRouter() *Router {
+	return &Router{
+		paramTypes: map[string]func(string) bool{
+			"int":  isInt,
+			"uint": isUint,
+			"hex":  regexp.MustCompile(`^[0-9a-fA-F]+$`).MatchString,
+			"uuid": regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+		},
+	}
+}
+
+func isInt(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+func isUint(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+// ParamType registers a parameter type, for patterns registered after it.
+func (router *Router) ParamType(name string, valid func(string) bool) {
+	router.paramTypes[name] = valid
+}
+
+// Handle registers a handler for a pattern, for all methods. It panics if the
+// pattern is invalid.
+func (router *Router) Handle(pattern string, handler RouteHandler) {
+	router.HandleMethod("", pattern, handler)
+}
+
+func (router *Router) GET(pattern string, handler RouteHandler) {
+	router.HandleMethod(go_http.MethodGet, pattern, handler)
+}
+
+func (router *Router) POST(pattern string, handler RouteHandler) {
+	router.HandleMethod(go_http.MethodPost, pattern, handler)
+}
+
+func (router *Router) PUT(pattern string, handler RouteHandler) {
+	router.HandleMethod(go_http.MethodPut, pattern, handler)
+}
+
+func (router *Router) PATCH(pattern string, handler RouteHandler) {
+	router.HandleMethod(go_http.MethodPatch, pattern, handler)
+}
+
+func (router *Router) DELETE(pattern string, handler RouteHandler) {
+	router.HandleMethod(go_http.MethodDelete, pattern, handler)
+}
+
+// HandleMethod registers a handler for a pattern and method. It panics if the
+// pattern is invalid.
+func (router *Router) HandleMethod(method, pattern string, handler RouteHandler) {
+	r, err := router.parsePattern(pattern)
+	if err != nil {
+		panic(err.Error())
+	}
+	r.method = method
+	r.handler = handler
+
+	// Keep the routes ordered by priority, so that the first match wins.
+	idx := sort.Search(len(router.routes), func(i int) bool {
+		return r.morePreciseThan(router.routes[i])
+	})
+	router.routes = append(router.routes, nil)
+	copy(router.routes[idx+1:], router.routes[idx:])
+	router.routes[idx] = r
+}
+
+// HandleRequest calls the handler of the best matching route. It returns nil
+// if no route matches the path.
+func (router *Router) HandleRequest(req *go_http.Request, urlParts []string, user User) Resolution {
+	r, params, allowed := router.find(req.Method, urlParts)
+	if r == nil && req.Method == go_http.MethodHead {
+		r, params, _ = router.find(go_http.MethodGet, urlParts)
+	}
+
+	switch {
+	case r != nil:
+		return r.handler(req, params, user)
+	case len(allowed) == 0:
+		return nil
+	case req.Method == go_http.MethodOptions:
+		return &ContentResolution{
+			Headers: []Header{{Name: "Allow", Value: allowHeader(allowed)}},
+		}
+	default:
+		return &MethodNotAllowedResolution{Allowed: allowHeader(allowed)}
+	}
+}
+
+// find returns the best route matching the method and path. If there is none,
+// it returns the methods of the routes matching the path.
+func (router *Router) find(method string, urlParts []string) (*route, Params, []string) {
+	var allowed []string
+	for _, r := range router.routes {
+		params, ok := r.match(urlParts)
+		switch {
+		case !ok:
+		case len(r.method) == 0 || r.method == method:
+			return r, params, nil
+		default:
+			allowed = append(allowed, r.method)
+		}
+	}
+	return nil, Params{}, allowed
+}
+
+func allowHeader(methods []string) string {
+	set := map[string]bool{go_http.MethodOptions: true}
+	for _, m := range methods {
+		set[m] = true
+		if m == go_http.MethodGet {
+			set[go_http.MethodHead] = true
+		}
+	}
+
+	list := make([]string, 0, len(set))
+	for m := range set {
+		list = append(list, m)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
+
+// ------------------------------------------------------------
+
+type segmentKind int
+
+// Segment kinds, from the most specific to the least.
+const (
+	segmentStatic segmentKind = iota
+	segmentTyped
+	segmentParam
+	segmentCatchAll
+)
+
+type segment struct {
+	kind  segmentKind
+	text  string // static text or parameter name
+	valid func(string) bool
+}
+
+type route struct {
+	method   string // "" for all methods
+	pattern  string
+	segments []segment
+	handler  RouteHandler
+}
+
+var paramPattern = regexp.MustCompile(`^:([A-Za-z_][A-Za-z0-9_]*)(?:<([A-Za-z0-9_]+)>)?$`)
+
+func (router *Router) parsePattern(pattern string) (*route, error) {
+	parts, res := splitUrlPath(pattern)
+	if res != nil {
+		return nil, fmt.Errorf("http.Router: invalid pattern '%s'", pattern)
+	}
+
+	r := &route{pattern: pattern}
+	for idx, part := range parts {
+		switch {
+		case strings.HasPrefix(part, ":"):
+			m := paramPattern.FindStringSubmatch(part)
+			if m == nil {
+				return nil, fmt.Errorf("http.Router: invalid parameter '%s' in '%s'", part, pattern)
+			}
+			if len(m[2]) == 0 {
+				r.segments = append(r.segments, segment{kind: segmentParam, text: m[1]})
+				continue
+			}
+			valid, ok := router.paramTypes[m[2]]
+			if !ok {
+				return nil, fmt.Errorf("http.Router: unknown parameter type '%s' in '%s'", m[2], pattern)
+			}
+			r.segments = append(r.segments, segment{kind: segmentTyped, text: m[1], valid: valid})
+		case strings.HasPrefix(part, "*"):
+			if idx != len(parts)-1 || len(part) == 1 {
+				return nil, fmt.Errorf("http.Router: invalid catch-all '%s' in '%s'", part, pattern)
+			}
+			r.segments = append(r.segments, segment{kind: segmentCatchAll, text: part[1:]})
+		default:
+			r.segments = append(r.segments, segment{kind: segmentStatic, text: part})
+		}
+	}
+	return r, nil
+}
+
+// morePreciseThan tells if r takes priority over other.
+func (r *route) morePreciseThan(other *route) bool {
+	for idx := 0; idx < len(r.segments) && idx < len(other.segments); idx++ {
+		if r.segments[idx].kind != other.segments[idx].kind {
+			return r.segments[idx].kind < other.segments[idx].kind
+		}
+	}
+	// Routes of different lengths can't match the same path unless their
+	// kinds differ; ordering them by length just keeps the order total.
+	return len(r.segments) < len(other.segments)
+}
+
+func (r *route) match(urlParts []string) (Params, bool) {
+	var params Params
+
+	for idx, seg := range r.segments {
+		if idx >= len(urlParts) {
+			return Params{}, false
+		}
+		part := urlParts[idx]
+
+		switch seg.kind {
+		case segmentStatic:
+			if part != seg.text {
+				return Params{}, false
+			}
+			continue
+		case segmentCatchAll:
+			rest := make([]string, 0, len(urlParts)-idx)
+			for _, p := range urlParts[idx:] {
+				value, err := url.PathUnescape(p)
+				if err != nil || !isPathSegment(value) {
+					return Params{}, false
+				}
+				rest = append(rest, value)
+			}
+			params.add(seg.text, strings.Join(rest, "/"))
+			return params, true
+		}
+
+		// A parameter needs a value: "/api/projects/" doesn't match
+		// "/api/projects/:id".
+		value, err := url.PathUnescape(part)
+		if err != nil || len(value) == 0 || (seg.valid != nil && !seg.valid(value)) {
+			return Params{}, false
+		}
+		params.add(seg.text, value)
+	}
+
+	if len(urlParts) != len(r.segments) {
+		return Params{}, false
+	}
+	return params, true
+}
+
+// isPathSegment tells if an unescaped catch-all segment is safe to join into
+// a path: "." and "..", and segments with an escaped "/" in them, would let
+// the path point outside the directory the caller meant.
+func isPathSegment(value string) bool {
+	return value != "." && value != ".." && !strings.Contains(value, "/")
+}
+
+// ------------------------------------------------------------
+
+// Params holds the unescaped values of the parameters of a matched route. A
+// catch-all holds the rest of the path, its segments joined with "/"; a path
+// with "." or ".." segments, or with an escaped "/" in a segment, doesn't match
+// a catch-all.
+type Params struct {
+	names  []string
+	values []string
+}
+
+func (p *Params) add(name, value string) {
+	p.names = append(p.names, name)
+	p.values = append(p.values, value)
+}
+
+// Get returns the value of a parameter, or "" if the route has no such
+// parameter.
+func (p Params) Get(name string) string {
+	for idx := range p.names {
+		if p.names[idx] == name {
+			return p.values[idx]
+		}
+	}
+	return ""
+}
+
+// Int returns the value of an integer parameter. Like RequireUser, it panics
+// with an ErrorResolution (Bad Request) if the value is not an integer.
+func (p Params) Int(name string) int64 {
+	value, err := strconv.ParseInt(p.Get(name), 10, 64)
+	if err != nil {
+		panic(&ErrorResolution{
+			Status:  go_http.StatusBadRequest,
+			Message: fmt.Sprintf("Parameter '%s' must be an integer", name),
+		})
+	}
+	return value
+}
+
+// Uint is like Int, for unsigned integers.
+func (p Params) Uint(name string) uint64 {
+	value, err := strconv.ParseUint(p.Get(name), 10, 64)
+	if err != nil {
+		panic(&ErrorResolution{
+			Status:  go_http.StatusBadRequest,
+			Message: fmt.Sprintf("Parameter '%s' must be an unsigned integer", name),
+		})
+	}
+	return value
+}
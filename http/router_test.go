@@ -0,0 +1,128 @@
+package http
+
+import (
+	"fmt"
+	go_http "net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func routeTo(name string) RouteHandler {
+	return func(req *go_http.Request, params Params, user User) Resolution {
+		return &ContentResolution{
+			Content: []byte(fmt.Sprintf("%s %v", name, params.values)),
+		}
+	}
+}
+
+func Test_Router(t *testing.T) {
+	// Setup
+	router := NewRouter()
+	router.Handle("/files/*path", routeTo("files"))
+	router.Handle("/api/projects/:name", routeTo("name"))
+	router.Handle("/api/projects/:id<int>", routeTo("id"))
+	router.Handle("/api/projects/new", routeTo("new"))
+	router.Handle("/api/projects/:id<int>/tasks/:task", routeTo("task"))
+	router.Handle("/files/:a/:b", routeTo("two"))
+
+	var data = []struct {
+		input string
+		want  string
+	}{
+		{"/api/projects/new", "new []"},
+		{"/api/projects/42", "id [42]"},
+		{"/api/projects/old%20one", "name [old one]"},
+		{"/api/projects/42/tasks/7", "task [42 7]"},
+		{"/api/projects/x/tasks/7", ""},
+		{"/api/projects", ""},
+		{"/api/projects/", ""},
+		{"/api/projects//tasks/7", ""},
+		{"/files/a", "files [a]"},
+		{"/files/a/b", "two [a b]"},
+		{"/files/a/b/c%20d", "files [a/b/c d]"},
+		{"/files/a/b/c%2Fd", ""},
+		{"/files/a/../../etc/passwd", ""},
+		{"/files/a/%2e%2e/%2E%2E/etc/passwd", ""},
+		{"/files/a/./b", ""},
+		{"/files/a/b/..", ""},
+		{"/files/a/b/..%2F..%2Fetc", ""},
+		{"/files/a/b/..%2f", ""},
+		{"/files/a/b/c..d", "files [a/b/c..d]"},
+	}
+
+	for _, d := range data {
+		req := httptest.NewRequest("GET", d.input, nil)
+		urlParts, _ := splitUrlPath(req.URL.EscapedPath())
+		res := router.HandleRequest(req, urlParts, nil)
+		var got string
+		if res != nil {
+			got = string(res.(*ContentResolution).Content)
+		}
+		if got != d.want {
+			t.Errorf("FAIL: %s: want %q, got %q", d.input, d.want, got)
+		}
+	}
+}
+
+func Test_RouterMethods(t *testing.T) {
+	// Setup
+	router := NewRouter()
+	router.GET("/projects/:id", routeTo("get"))
+	router.DELETE("/projects/:id", routeTo("delete"))
+	router.POST("/projects/new", routeTo("create"))
+	router.Handle("/any", routeTo("any"))
+
+	var data = []struct {
+		method string
+		input  string
+		status int
+		want   string
+		allow  string
+	}{
+		{"GET", "/projects/1", 200, "get [1]", ""},
+		{"HEAD", "/projects/1", 200, "get [1]", ""},
+		{"DELETE", "/projects/new", 200, "delete [new]", ""},
+		{"POST", "/projects/new", 200, "create []", ""},
+		{"POST", "/projects/1", 405, "", "DELETE, GET, HEAD, OPTIONS"},
+		{"OPTIONS", "/projects/new", 204, "", "DELETE, GET, HEAD, OPTIONS, POST"},
+		{"PUT", "/any", 200, "any []", ""},
+	}
+
+	for _, d := range data {
+		req := httptest.NewRequest(d.method, d.input, nil)
+		urlParts, _ := splitUrlPath(req.URL.EscapedPath())
+		res := router.HandleRequest(req, urlParts, nil)
+		out := httptest.NewRecorder()
+		res.WriteResponse(out, req)
+		if res.StatusCode() != d.status || out.Code != d.status {
+			t.Errorf("FAIL: %s %s: want status %d, got %d", d.method, d.input, d.status, res.StatusCode())
+		}
+		if content, ok := res.(*ContentResolution); ok && len(d.want) > 0 && string(content.Content) != d.want {
+			t.Errorf("FAIL: %s %s: want %q, got %q", d.method, d.input, d.want, content.Content)
+		}
+		if allow := out.Header().Get("Allow"); allow != d.allow {
+			t.Errorf("FAIL: %s %s: want Allow %q, got %q", d.method, d.input, d.allow, allow)
+		}
+	}
+}
+
+func Test_RouterInvalidPattern(t *testing.T) {
+	var data = []string{
+		"projects",
+		"/projects/:",
+		"/projects/:id<float>",
+		"/files/*path/more",
+		"/files/*",
+	}
+
+	for _, pattern := range data {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("FAIL: %q was accepted", pattern)
+				}
+			}()
+			NewRouter().Handle(pattern, routeTo("x"))
+		}()
+	}
+}
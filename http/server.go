@@ -1,13 +1,20 @@
 package http
 
 import (
+	"context"
+	"io/fs"
+	"net"
 	go_http "net/http"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pjsaksa/go-utils/log"
 )
 
+var logger = log.Named("http")
+
+// ------------------------------------------------------------
+
 type ServerController interface {
 	BindAddress() string
 	SessionCookieName() string
@@ -18,6 +25,10 @@ type ServerController interface {
 	MessageSummary(*go_http.Request, Resolution)
 
 	Login(user, password string) User
+
+	// Unless the controller is a SessionStoreController, LoadSessions fills
+	// the initial sessions, and RefreshSession is called with all of them
+	// whenever the session of a token changes.
 	LoadSessions(SessionMap)
 	RefreshSession(string, SessionMap)
 }
@@ -35,20 +46,46 @@ type SessionMap map[string]*Session
 
 // ------------------------------------------------------------
 
+// RequestHandler handles a request, given its URL path split into parts and
+// the signed-in user (nil if none).
+type RequestHandler func(req *go_http.Request, urlParts []string, user User) Resolution
+
+// Middleware wraps a RequestHandler, for concerns common to all requests. It
+// may pass on a modified request or user, replace or decorate the resolution
+// returned by next, or return a resolution of its own without calling next.
+type Middleware func(next RequestHandler) RequestHandler
+
+// ------------------------------------------------------------
+
 type Server struct {
-	ctrl          ServerController
-	httpServer    go_http.Server
-	sessions      SessionMap
-	sessionsMutex sync.Mutex
+	ctrl       ServerController
+	httpServer go_http.Server
+	sessions   SessionStore
+	lastExpire atomic.Int64
+	middleware []Middleware
+	accessLog  AccessLogFormat
+	socketMode fs.FileMode
+
+	// Request contexts derive from baseContext, which is canceled when
+	// shutdown has drained the ordinary requests.
+	baseContext context.Context
+	cancelBase  context.CancelFunc
+	webSockets  activeCounter
 }
 
 func NewServer(ctrl ServerController) *Server {
 	srv := &Server{
-		ctrl:     ctrl,
-		sessions: SessionMap{},
+		ctrl:       ctrl,
+		socketMode: DefaultSocketMode,
 	}
+	srv.baseContext, srv.cancelBase = context.WithCancel(context.Background())
 
-	ctrl.LoadSessions(srv.sessions)
+	if storeCtrl, ok := ctrl.(SessionStoreController); ok {
+		srv.sessions = storeCtrl.SessionStore()
+	}
+	if srv.sessions == nil {
+		srv.sessions = newControllerSessionStore(ctrl)
+	}
 
 	srv.httpServer = go_http.Server{
 		Addr:           srv.ctrl.BindAddress(),
@@ -57,51 +94,94 @@ func NewServer(ctrl ServerController) *Server {
 	}
 
 	ctrl.ConfigureHttpServer(&srv.httpServer)
+	srv.httpServer.BaseContext = srv.wrapBaseContext(srv.httpServer.BaseContext)
 	return srv
 }
 
-func (srv *Server) Start() {
-	log.INFO("Listening HTTP at %s", srv.httpServer.Addr)
-	if err := srv.httpServer.ListenAndServe(); err != go_http.ErrServerClosed {
-		panic(err.Error())
+// wrapBaseContext makes the request contexts derive from baseContext, and
+// also from the contexts of base if the controller set a BaseContext.
+func (srv *Server) wrapBaseContext(base func(net.Listener) context.Context) func(net.Listener) context.Context {
+	if base == nil {
+		return func(net.Listener) context.Context { return srv.baseContext }
+	}
+	return func(listener net.Listener) context.Context {
+		ctx, cancel := context.WithCancel(base(listener))
+		go func() {
+			select {
+			case <-srv.baseContext.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		return ctx
 	}
 }
 
-func (srv *Server) StartTLS(certFile, keyFile string) {
-	log.INFO("Listening HTTPS at %s", srv.httpServer.Addr)
-	if err := srv.httpServer.ListenAndServeTLS(certFile, keyFile); err != go_http.ErrServerClosed {
-		panic(err.Error())
+// Use adds middleware around the handling of requests. The first middleware
+// added is the outermost: it sees the request first and the resolution last.
+// The innermost handler signs users in and out, and calls the controller's
+// HandleRequest; Resolution panics (RequireUser...) are returned to the
+// middleware as resolutions. Use must be called before the server is started.
+//
+// The URL path is split, and the session cookie resolved, before the
+// middleware is called. Requests failing there never reach the middleware:
+// those with a malformed path (Bad Request), and those with an unknown or
+// expired session cookie (cleared, and redirected to "/"). The access log and
+// MessageSummary see all requests.
+func (srv *Server) Use(mw ...Middleware) {
+	srv.middleware = append(srv.middleware, mw...)
+}
+
+// Start serves HTTP at the bind address (see Listen) until the server is shut
+// down, and then returns nil.
+func (srv *Server) Start() error {
+	listener, err := Listen(srv.httpServer.Addr, srv.socketMode)
+	if err != nil {
+		return err
 	}
+	return srv.Serve(listener)
+}
+
+// StartTLS is like Start, for HTTPS.
+func (srv *Server) StartTLS(certFile, keyFile string) error {
+	listener, err := Listen(srv.httpServer.Addr, srv.socketMode)
+	if err != nil {
+		return err
+	}
+	return srv.ServeTLS(listener, certFile, keyFile)
 }
 
 // ------------------------------------------------------------
 
 func (srv *Server) ServeHTTP(out go_http.ResponseWriter, req *go_http.Request) {
 	var cookies []*go_http.Cookie
+	start := time.Now()
+
+	// Messages logged through the logger of the request context carry its
+	// trace id.
+	req = req.WithContext(log.ContextWithTrace(req.Context(), requestTrace(req)))
 
 	// Handle request
-	resolution := srv.handleRequest(req, &cookies)
+	resolution, user := srv.handleRequest(req, &cookies)
 
 	// Produce response
 	for _, c := range cookies {
 		go_http.SetCookie(out, c)
 	}
+	if _, ok := resolution.(*WebSocketResolution); ok {
+		srv.webSockets.add()
+		defer srv.webSockets.done()
+	}
 	resolution.WriteResponse(out, req)
 
+	srv.logAccess(req, resolution, user, start)
 	srv.ctrl.MessageSummary(req, resolution)
 }
 
-func (srv *Server) handleRequest(req *go_http.Request, cookies *[]*go_http.Cookie) (resolution Resolution) {
-	defer func() {
-		if err := recover(); err != nil {
-			switch errT := err.(type) {
-			case Resolution:
-				resolution = errT
-			default:
-				panic(err)
-			}
-		}
-	}()
+// handleRequest returns the resolution of the request and the user it was
+// handled for.
+func (srv *Server) handleRequest(req *go_http.Request, cookies *[]*go_http.Cookie) (resolution Resolution, handledUser User) {
+	defer recoverResolution(&resolution)
 
 	var urlParts []string
 	urlParts, resolution = splitUrlPath(req.URL.EscapedPath())
@@ -109,41 +189,70 @@ func (srv *Server) handleRequest(req *go_http.Request, cookies *[]*go_http.Cooki
 		return
 	}
 
-	var sessionUser User
-	sessionUser, resolution = srv.handleSessions(urlParts, req, cookies)
-	if resolution != nil {
-		return
+	// A verified client certificate takes precedence over session cookies,
+	// which are then left alone.
+	sessionUser := srv.clientCertUser(req)
+	var sessionCookie string
+	if sessionUser == nil {
+		sessionUser, sessionCookie = srv.getOpenSession(req, cookies)
 	}
 
-	resolution = srv.ctrl.HandleRequest(req, urlParts, sessionUser)
-	if resolution != nil {
-		return
-	}
+	handler := func(req *go_http.Request, urlParts []string, user User) (resolution Resolution) {
+		defer recoverResolution(&resolution)
+		handledUser = user
+
+		resolution = srv.handleSessions(urlParts, req, cookies, user, sessionCookie)
+		if resolution != nil {
+			return
+		}
+
+		resolution = srv.ctrl.HandleRequest(req, urlParts, user)
+		if resolution != nil {
+			return
+		}
 
-	resolution = &ErrorResolution{Status: go_http.StatusNotFound}
+		return &ErrorResolution{Status: go_http.StatusNotFound}
+	}
+	for idx := len(srv.middleware) - 1; idx >= 0; idx-- {
+		handler = srv.middleware[idx](handler)
+	}
 
+	resolution = handler(req, urlParts, sessionUser)
+	if resolution == nil {
+		resolution = &ErrorResolution{Status: go_http.StatusNotFound}
+	}
 	return
 }
 
-func (srv *Server) handleSessions(urlParts []string, req *go_http.Request, cookies *[]*go_http.Cookie) (User, Resolution) {
-	// Check if request contains session information.
-	sessionUser, sessionCookie := srv.getOpenSession(req, cookies)
+// recoverResolution turns a panic with a Resolution, such as the one of
+// RequireUser, into the returned resolution. It must be deferred directly.
+func recoverResolution(resolution *Resolution) {
+	if err := recover(); err != nil {
+		switch errT := err.(type) {
+		case Resolution:
+			*resolution = errT
+		default:
+			panic(err)
+		}
+	}
+}
 
+func (srv *Server) handleSessions(urlParts []string, req *go_http.Request, cookies *[]*go_http.Cookie, sessionUser User, sessionCookie string) Resolution {
 	if urlParts[0] == "u" {
 		// User-specific page handler.
 
 		if sessionUser == nil {
-			return nil, &ErrorResolution{Status: go_http.StatusForbidden}
+			return &ErrorResolution{Status: go_http.StatusForbidden}
 		}
 
 		if UrlPartsMatch(urlParts, "u", "sign-out") {
-			return nil, srv.doSignOut(req, cookies, sessionUser, sessionCookie)
+			return srv.doSignOut(req, cookies, sessionUser, sessionCookie)
 		}
 	} else {
 		if UrlPartsMatch(urlParts, "sign-in") {
-			return nil, srv.doSignIn(req, cookies)
+			return srv.doSignIn(req, cookies)
 		}
 	}
 
-	return sessionUser, nil
+	return nil
 }
@@ -0,0 +1,234 @@
+package http
+
+import (
+	"fmt"
+	go_http "net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pjsaksa/go-utils/log"
+)
+
+type testController struct {
+	handler func(*go_http.Request, []string, User) Resolution
+}
+
+func (ctrl *testController) BindAddress() string                 { return "127.0.0.1:0" }
+func (ctrl *testController) SessionCookieName() string           { return "session" }
+func (ctrl *testController) SessionMaxAge() time.Duration        { return time.Hour }
+func (ctrl *testController) ConfigureHttpServer(*go_http.Server) {}
+
+func (ctrl *testController) HandleRequest(req *go_http.Request, urlParts []string, user User) Resolution {
+	return ctrl.handler(req, urlParts, user)
+}
+
+func (ctrl *testController) MessageSummary(*go_http.Request, Resolution) {}
+func (ctrl *testController) Login(user, password string) User            { return nil }
+func (ctrl *testController) LoadSessions(SessionMap)                     {}
+func (ctrl *testController) RefreshSession(string, SessionMap)           {}
+
+// ------------------------------------------------------------
+
+func Test_ServerTrace(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	log.SetOutput(&out)
+	defer log.ResetOutput()
+
+	var trace log.TraceContext
+	srv := NewServer(&testController{
+		handler: func(req *go_http.Request, urlParts []string, user User) Resolution {
+			trace = TraceFromRequest(req)
+			log.FromContext(req.Context()).INFO("handling")
+			return &ContentResolution{Content: []byte("ok")}
+		},
+	})
+
+	// Test object
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=1")
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Verify: the trace continues with a new span, and the log line carries it.
+	if trace.TraceIDString() != traceID || trace.SpanIDString() == "00f067aa0ba902b7" || trace.State != "vendor=1" {
+		t.Errorf("Unexpected trace context %s (%q)", trace.Traceparent(), trace.State)
+	}
+	if !strings.Contains(out.String(), "trace_id="+traceID+" span_id="+trace.SpanIDString()) {
+		t.Errorf("Log output lacks trace ids:\n%s", out.String())
+	}
+}
+
+type testUser string
+
+func (u testUser) Username() string { return string(u) }
+
+func Test_ServerMiddleware(t *testing.T) {
+	// Setup
+	var order []string
+	srv := NewServer(&testController{
+		handler: func(req *go_http.Request, urlParts []string, user User) Resolution {
+			order = append(order, "handler")
+			RequireUser(user)
+			return &ContentResolution{Content: []byte(user.Username())}
+		},
+	})
+
+	trace := func(name string) Middleware {
+		return func(next RequestHandler) RequestHandler {
+			return func(req *go_http.Request, urlParts []string, user User) Resolution {
+				order = append(order, name+" in")
+				res := next(req, urlParts, user)
+				order = append(order, fmt.Sprintf("%s out %d", name, res.StatusCode()))
+				return res
+			}
+		}
+	}
+	auth := func(next RequestHandler) RequestHandler {
+		return func(req *go_http.Request, urlParts []string, user User) Resolution {
+			if token := req.Header.Get("X-Token"); len(token) > 0 {
+				user = testUser(token)
+			}
+			return next(req, urlParts, user)
+		}
+	}
+	srv.Use(trace("first"), trace("second"))
+	srv.Use(auth)
+
+	// Test object
+	srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Token", "alice")
+	out := httptest.NewRecorder()
+	srv.ServeHTTP(out, req)
+
+	rejected := httptest.NewRequest("GET", "/", nil)
+	rejected.AddCookie(&go_http.Cookie{Name: "session", Value: "unknown"})
+	redirect := httptest.NewRecorder()
+	srv.ServeHTTP(redirect, rejected)
+
+	// Verify: the middleware runs in order and sees the Forbidden panic of
+	// RequireUser as a resolution. A rejected session cookie is answered
+	// before the middleware.
+	expected := []string{
+		"first in", "second in", "handler", "second out 403", "first out 403",
+		"first in", "second in", "handler", "second out 200", "first out 200",
+	}
+	if strings.Join(order, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+			strings.Join(expected, "\n"),
+			strings.Join(order, "\n"))
+	}
+	if out.Body.String() != "alice" {
+		t.Errorf("Unexpected response %q", out.Body.String())
+	}
+	if redirect.Code != go_http.StatusSeeOther {
+		t.Errorf("Unexpected status %d for a rejected session", redirect.Code)
+	}
+}
+
+type baseTestController struct {
+	BaseController
+}
+
+func (ctrl *baseTestController) HandleRequest(req *go_http.Request, urlParts []string, user User) Resolution {
+	RequireUser(user)
+	return &ContentResolution{Content: []byte("hello " + user.Username())}
+}
+
+func Test_BaseController(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	log.SetOutput(&out)
+	log.SetColor(false)
+
+	// Cleanup
+	defer func() {
+		log.ResetOutput()
+		log.ResetColor()
+	}()
+
+	ctrl := &baseTestController{
+		BaseController: NewBaseController(
+			WithCookieName("sid"),
+			WithLogin(func(user, password string) User {
+				if password == "secret" {
+					return testUser(user)
+				}
+				return nil
+			}),
+		),
+	}
+	srv := NewServer(ctrl)
+
+	// Test object
+	signIn := httptest.NewRequest("POST", "/sign-in", strings.NewReader("user=alice&password=secret"))
+	signIn.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	signInOut := httptest.NewRecorder()
+	srv.ServeHTTP(signInOut, signIn)
+
+	req := httptest.NewRequest("GET", "/u/", nil)
+	for _, c := range signInOut.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	reqOut := httptest.NewRecorder()
+	srv.ServeHTTP(reqOut, req)
+
+	// Verify output.
+	if ctrl.BindAddress() != ":8080" || ctrl.SessionCookieName() != "sid" {
+		t.Errorf("Unexpected settings %q, %q", ctrl.BindAddress(), ctrl.SessionCookieName())
+	}
+	if reqOut.Body.String() != "hello alice" {
+		t.Errorf("Unexpected response %d %q", reqOut.Code, reqOut.Body.String())
+	}
+	if !strings.Contains(out.String(), "[http] GET /u/ -> 11 bytes trace_id=") {
+		t.Errorf("Unexpected message summary:\n%s", out.String())
+	}
+}
+
+func Test_BaseControllerWithoutLogin(t *testing.T) {
+	// Setup
+	srv := NewServer(&baseTestController{
+		BaseController: NewBaseController(
+			WithLogin(func(user, password string) User { return testUser(user) }),
+			WithoutLogin(),
+		),
+	})
+
+	// Test object
+	req := httptest.NewRequest("POST", "/sign-in", strings.NewReader("user=alice&password=secret"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	out := httptest.NewRecorder()
+	srv.ServeHTTP(out, req)
+
+	// Verify output.
+	if out.Code != go_http.StatusForbidden || len(out.Result().Cookies()) > 0 {
+		t.Errorf("Sign-in succeeded: %d %v", out.Code, out.Result().Cookies())
+	}
+}
+
+func Test_BaseControllerMemorySessions(t *testing.T) {
+	// Setup
+	logStore, err := NewLogSessionStore(filepath.Join(t.TempDir(), "sessions.log"), lookupTestUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logStore.Close()
+
+	// Test object
+	ctrl := NewBaseController(WithSessionStore(logStore), WithMemorySessions())
+	srv := NewServer(&baseTestController{BaseController: ctrl})
+
+	// Verify output.
+	if _, ok := ctrl.SessionStore().(*MemorySessionStore); !ok {
+		t.Errorf("Unexpected session store %T", ctrl.SessionStore())
+	}
+	if srv.sessions != ctrl.SessionStore() {
+		t.Errorf("The server doesn't use the session store of the controller")
+	}
+}
@@ -6,10 +6,12 @@ import (
 	"fmt"
 	go_http "net/http"
 	"time"
-
-	"github.com/pjsaksa/go-utils/log"
 )
 
+var sessionLogger = logger.Named("session")
+
+// ------------------------------------------------------------
+
 func (srv *Server) doSignIn(req *go_http.Request, cookies *[]*go_http.Cookie) Resolution {
 	if req.Method != "POST" {
 		return &MethodNotAllowedResolution{Allowed: "POST"}
@@ -19,29 +21,23 @@ func (srv *Server) doSignIn(req *go_http.Request, cookies *[]*go_http.Cookie) Re
 	p := req.PostFormValue("password")
 	if u != "" {
 		if user := srv.ctrl.Login(u, p); user != nil {
-			var token string
+			srv.expireSessions()
 
-			// Mutex-zone
-			func() {
-				srv.sessionsMutex.Lock()
-				defer srv.sessionsMutex.Unlock()
-
-				// Create tokens until a fresh one is found
-				for {
-					token = newSessionToken()
-					if _, exists := srv.sessions[token]; !exists {
-						break
-					}
+			// Create tokens until a fresh one is found
+			var token string
+			for {
+				token = newSessionToken()
+				if session := srv.getSession(token); session == nil {
+					break
 				}
+			}
 
-				srv.sessions[token] = &Session{
-					User:        user,
-					RefreshTime: time.Now(),
-				}
-				srv.ctrl.RefreshSession(token, srv.sessions)
-			}()
+			srv.checkStore(srv.sessions.Put(token, &Session{
+				User:        user,
+				RefreshTime: time.Now(),
+			}))
 
-			log.INFO("Sign-in '%s'", u)
+			sessionLogger.ForContext(req.Context()).EVENT("Sign-in '%s'", u)
 
 			*cookies = append(*cookies, &go_http.Cookie{
 				Name:   srv.ctrl.SessionCookieName(),
@@ -67,16 +63,12 @@ func (srv *Server) doSignOut(req *go_http.Request, cookies *[]*go_http.Cookie, a
 		return &MethodNotAllowedResolution{Allowed: "POST"}
 	}
 
-	// Mutex-zone
-	func() {
-		srv.sessionsMutex.Lock()
-		defer srv.sessionsMutex.Unlock()
+	// A user of a client certificate has no session to end.
+	if len(activeCookie) > 0 {
+		srv.checkStore(srv.sessions.Delete(activeCookie))
 
-		delete(srv.sessions, activeCookie)
-		srv.ctrl.RefreshSession(activeCookie, srv.sessions)
-	}()
-
-	log.INFO("Sign-out '%s'", activeUser.Username())
+		sessionLogger.ForContext(req.Context()).EVENT("Sign-out '%s'", activeUser.Username())
+	}
 
 	*cookies = append(*cookies, &go_http.Cookie{
 		Name:   srv.ctrl.SessionCookieName(),
@@ -93,29 +85,16 @@ func (srv *Server) doSignOut(req *go_http.Request, cookies *[]*go_http.Cookie, a
 
 func (srv *Server) getOpenSession(req *go_http.Request, cookies *[]*go_http.Cookie) (User, string) {
 	if cookie, err := req.Cookie(srv.ctrl.SessionCookieName()); err != go_http.ErrNoCookie && cookie != nil && len(cookie.Value) > 0 {
-		srv.sessionsMutex.Lock()
-		defer srv.sessionsMutex.Unlock()
-
-		session, ok := srv.sessions[cookie.Value]
+		session := srv.getSession(cookie.Value)
+		ok := session != nil
 		if !ok {
-			log.WARNING("Requested session not found")
-		}
-
-		if ok && session == nil {
-			// SessionMap contains nil entry. Make noise because this needs to
-			// be tracked down.
-			log.ERROR(`http.Server.getOpenSession: "sessions" had nil entry: %s`, cookie.Value)
-
-			// Delete invalid session entry
-			delete(srv.sessions, cookie.Value)
-			srv.ctrl.RefreshSession(cookie.Value, srv.sessions)
-
-			ok = false
+			sessionLogger.ForContext(req.Context()).WARNING("Requested session not found")
 		}
 
 		if ok && time.Since(session.RefreshTime) > srv.ctrl.SessionMaxAge() {
 			// Session has expired
-			log.INFO("Session expired '%s'", session.User.Username())
+			sessionLogger.ForContext(req.Context()).INFO("Session expired '%s'", session.User.Username())
+			srv.checkStore(srv.sessions.Delete(cookie.Value))
 
 			ok = false
 		}
@@ -123,8 +102,7 @@ func (srv *Server) getOpenSession(req *go_http.Request, cookies *[]*go_http.Cook
 		if ok {
 			// Refresh session (unless it's fresh enough)
 			if time.Since(session.RefreshTime) > time.Hour {
-				session.RefreshTime = time.Now()
-				srv.ctrl.RefreshSession(cookie.Value, srv.sessions)
+				srv.checkStore(srv.sessions.Touch(cookie.Value, time.Now()))
 
 				*cookies = append(*cookies, &go_http.Cookie{
 					Name:   srv.ctrl.SessionCookieName(),
@@ -157,6 +135,37 @@ func (srv *Server) getOpenSession(req *go_http.Request, cookies *[]*go_http.Cook
 	return nil, ""
 }
 
+func (srv *Server) getSession(token string) *Session {
+	session, err := srv.sessions.Get(token)
+	srv.checkStore(err)
+	return session
+}
+
+// checkStore fails the request if the session store failed.
+func (srv *Server) checkStore(err error) {
+	if err != nil {
+		panic(&ErrorResolution{
+			Status:  go_http.StatusInternalServerError,
+			Message: fmt.Sprintf("http.SessionStore: %s", err.Error()),
+		})
+	}
+}
+
+// expireSessions deletes expired sessions from the store, at most once per
+// expireInterval.
+func (srv *Server) expireSessions() {
+	const expireInterval = time.Hour
+
+	last := srv.lastExpire.Load()
+	now := time.Now().UnixNano()
+	if now-last < int64(expireInterval) || !srv.lastExpire.CompareAndSwap(last, now) {
+		return
+	}
+	if err := srv.sessions.Expire(srv.ctrl.SessionMaxAge()); err != nil {
+		sessionLogger.ERROR("Expiring sessions failed: %s", err)
+	}
+}
+
 // ------------------------------------------------------------
 
 func newSessionToken() string {
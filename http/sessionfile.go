@@ -0,0 +1,413 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// File-based session stores save users by name. lookupUser turns a name back
+// into a User when the sessions are loaded; sessions of users it returns nil
+// for are dropped. Session files hold session tokens, so they are created
+// readable by the owner only.
+
+type sessionEntry struct {
+	Op          string    `json:"op,omitempty"`
+	Token       string    `json:"token,omitempty"`
+	User        string    `json:"user,omitempty"`
+	RefreshTime time.Time `json:"refresh_time,omitempty"`
+}
+
+// writeFileAtomic replaces a file, so that readers see either the old or the
+// new content, also after a crash. The new content is written to a temporary
+// file in the same directory, synced to disk, and renamed over the old one.
+func writeFileAtomic(fileName string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), fileName); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(fileName))
+}
+
+// ------------------------------------------------------------
+
+// JSONFileSessionStore keeps sessions in memory and saves all of them to a
+// JSON file, at most once per JSONSaveDelay. Every save rewrites the whole
+// file, which suits a modest number of sessions; LogSessionStore scales to
+// many. Saving errors are logged, and changes made within JSONSaveDelay
+// before a crash are lost. Close saves the pending changes.
+type JSONFileSessionStore struct {
+	mutex    sync.Mutex
+	fileName string
+	sessions SessionMap
+	timer    *time.Timer // pending save, if any
+}
+
+// JSONSaveDelay is how long JSONFileSessionStore gathers changes before
+// saving them.
+var JSONSaveDelay = time.Second
+
+func NewJSONFileSessionStore(fileName string, lookupUser func(username string) User) (*JSONFileSessionStore, error) {
+	store := &JSONFileSessionStore{
+		fileName: fileName,
+		sessions: SessionMap{},
+	}
+
+	data, err := os.ReadFile(fileName)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return store, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var entries map[string]sessionEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	for token, entry := range entries {
+		if user := lookupUser(entry.User); user != nil {
+			store.sessions[token] = &Session{User: user, RefreshTime: entry.RefreshTime}
+		}
+	}
+	return store, nil
+}
+
+// changed schedules a save, unless one is pending already.
+func (store *JSONFileSessionStore) changed() {
+	if store.timer == nil {
+		store.timer = time.AfterFunc(JSONSaveDelay, store.flush)
+	}
+}
+
+func (store *JSONFileSessionStore) flush() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.timer = nil
+	if err := store.save(); err != nil {
+		sessionLogger.ERROR("Saving sessions to %s failed: %s", store.fileName, err)
+	}
+}
+
+func (store *JSONFileSessionStore) save() error {
+	entries := make(map[string]sessionEntry, len(store.sessions))
+	for token, session := range store.sessions {
+		entries[token] = sessionEntry{
+			User:        session.User.Username(),
+			RefreshTime: session.RefreshTime,
+		}
+	}
+
+	data, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(store.fileName, data)
+}
+
+func (store *JSONFileSessionStore) Get(token string) (*Session, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.sessions.get(token), nil
+}
+
+func (store *JSONFileSessionStore) Put(token string, session *Session) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.sessions.put(token, session)
+	store.changed()
+	return nil
+}
+
+func (store *JSONFileSessionStore) Delete(token string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.sessions[token]; !ok {
+		return nil
+	}
+	delete(store.sessions, token)
+	store.changed()
+	return nil
+}
+
+func (store *JSONFileSessionStore) Touch(token string, refreshTime time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.sessions.touch(token, refreshTime) {
+		store.changed()
+	}
+	return nil
+}
+
+func (store *JSONFileSessionStore) List() (SessionMap, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.sessions.copy(), nil
+}
+
+func (store *JSONFileSessionStore) Expire(maxAge time.Duration) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if len(store.sessions.expire(maxAge)) > 0 {
+		store.changed()
+	}
+	return nil
+}
+
+// Close saves the pending changes, if any. Changes made afterwards are saved
+// as before.
+func (store *JSONFileSessionStore) Close() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.timer == nil {
+		return nil
+	}
+	// If the timer has fired already, flush saves once more after this.
+	store.timer.Stop()
+	store.timer = nil
+	return store.save()
+}
+
+// ------------------------------------------------------------
+
+// LogSessionStore keeps sessions in memory and appends every change to a log
+// file, one JSON object per line. The log is compacted when it is opened and
+// whenever it has grown well beyond the number of sessions.
+type LogSessionStore struct {
+	mutex    sync.Mutex
+	fileName string
+	file     *os.File
+	lines    int
+	sessions SessionMap
+}
+
+// logCompactSlack is the number of surplus log lines tolerated before
+// compaction.
+const logCompactSlack = 1024
+
+func NewLogSessionStore(fileName string, lookupUser func(username string) User) (*LogSessionStore, error) {
+	store := &LogSessionStore{
+		fileName: fileName,
+		sessions: SessionMap{},
+	}
+
+	if err := store.replay(lookupUser); err != nil {
+		return nil, err
+	}
+	if err := store.compact(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (store *LogSessionStore) replay(lookupUser func(username string) User) error {
+	file, err := os.Open(store.fileName)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry sessionEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A line cut short by a crash; the ones before it still count.
+			continue
+		}
+
+		switch entry.Op {
+		case "put":
+			if user := lookupUser(entry.User); user != nil {
+				store.sessions[entry.Token] = &Session{User: user, RefreshTime: entry.RefreshTime}
+			}
+		case "touch":
+			store.sessions.touch(entry.Token, entry.RefreshTime)
+		case "delete":
+			delete(store.sessions, entry.Token)
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the log with one line per session, and reopens it.
+func (store *LogSessionStore) compact() error {
+	var data []byte
+	for token, session := range store.sessions {
+		line, err := json.Marshal(sessionEntry{
+			Op:          "put",
+			Token:       token,
+			User:        session.User.Username(),
+			RefreshTime: session.RefreshTime,
+		})
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	if err := writeFileAtomic(store.fileName, data); err != nil {
+		return err
+	}
+
+	if store.file != nil {
+		store.file.Close()
+	}
+	file, err := os.OpenFile(store.fileName, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		store.file = nil
+		return err
+	}
+	store.file = file
+	store.lines = len(store.sessions)
+	return nil
+}
+
+// append writes an entry to the log. Changes are logged before they are made
+// in memory, so that the memory never holds a change the log doesn't.
+func (store *LogSessionStore) append(entry sessionEntry) error {
+	if store.file == nil {
+		return errors.New("http.LogSessionStore: closed")
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := store.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	store.lines++
+	return nil
+}
+
+// compactIfNeeded compacts the log if it has grown well beyond the number of
+// sessions.
+func (store *LogSessionStore) compactIfNeeded() error {
+	if store.lines > 2*len(store.sessions)+logCompactSlack {
+		return store.compact()
+	}
+	return nil
+}
+
+func (store *LogSessionStore) Get(token string) (*Session, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.sessions.get(token), nil
+}
+
+func (store *LogSessionStore) Put(token string, session *Session) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if err := store.append(sessionEntry{
+		Op:          "put",
+		Token:       token,
+		User:        session.User.Username(),
+		RefreshTime: session.RefreshTime,
+	}); err != nil {
+		return err
+	}
+	store.sessions.put(token, session)
+	return store.compactIfNeeded()
+}
+
+func (store *LogSessionStore) Delete(token string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.sessions[token]; !ok {
+		return nil
+	}
+	if err := store.append(sessionEntry{Op: "delete", Token: token}); err != nil {
+		return err
+	}
+	delete(store.sessions, token)
+	return store.compactIfNeeded()
+}
+
+func (store *LogSessionStore) Touch(token string, refreshTime time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.sessions[token]; !ok {
+		return nil
+	}
+	if err := store.append(sessionEntry{Op: "touch", Token: token, RefreshTime: refreshTime}); err != nil {
+		return err
+	}
+	store.sessions.touch(token, refreshTime)
+	return store.compactIfNeeded()
+}
+
+func (store *LogSessionStore) List() (SessionMap, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.sessions.copy(), nil
+}
+
+func (store *LogSessionStore) Expire(maxAge time.Duration) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// A session that can't be logged as deleted is kept, and the rest are
+	// still expired; the first error is returned.
+	var firstErr error
+	for _, token := range store.sessions.expired(maxAge) {
+		if err := store.append(sessionEntry{Op: "delete", Token: token}); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		delete(store.sessions, token)
+	}
+	if err := store.compactIfNeeded(); firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// Close closes the log file. The store can't be changed afterwards.
+func (store *LogSessionStore) Close() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.file == nil {
+		return nil
+	}
+	err := store.file.Close()
+	store.file = nil
+	return err
+}
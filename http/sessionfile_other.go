@@ -0,0 +1,8 @@
+//go:build !unix
+
+package http
+
+// syncDir does nothing on systems where directories can't be synced.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package http
+
+import "os"
+
+// syncDir syncs a directory, so that a file renamed into it stays there after
+// a crash.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return file.Sync()
+}
@@ -0,0 +1,225 @@
+package http
+
+import (
+	"sync"
+	"time"
+)
+
+// SessionStore keeps the sessions of a Server. Methods are called
+// concurrently, and sessions are passed in and out by copy. A store that is
+// also an io.Closer is closed by Server.Shutdown once the last request is
+// done.
+type SessionStore interface {
+	// Get returns the session of a token, or nil if there is none.
+	Get(token string) (*Session, error)
+	Put(token string, session *Session) error
+	Delete(token string) error
+	// Touch sets the refresh time of a session.
+	Touch(token string, refreshTime time.Time) error
+	List() (SessionMap, error)
+	// Expire deletes the sessions not refreshed within maxAge.
+	Expire(maxAge time.Duration) error
+}
+
+// SessionStoreController is implemented by controllers that provide a
+// SessionStore. For other controllers, and if SessionStore returns nil, the
+// server keeps the sessions in memory and reports changes through
+// LoadSessions and RefreshSession.
+type SessionStoreController interface {
+	SessionStore() SessionStore
+}
+
+// ------------------------------------------------------------
+
+// MemorySessionStore keeps sessions in memory only.
+type MemorySessionStore struct {
+	mutex    sync.Mutex
+	sessions SessionMap
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: SessionMap{}}
+}
+
+func (store *MemorySessionStore) Get(token string) (*Session, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.sessions.get(token), nil
+}
+
+func (store *MemorySessionStore) Put(token string, session *Session) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.sessions.put(token, session)
+	return nil
+}
+
+func (store *MemorySessionStore) Delete(token string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.sessions, token)
+	return nil
+}
+
+func (store *MemorySessionStore) Touch(token string, refreshTime time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.sessions.touch(token, refreshTime)
+	return nil
+}
+
+func (store *MemorySessionStore) List() (SessionMap, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.sessions.copy(), nil
+}
+
+func (store *MemorySessionStore) Expire(maxAge time.Duration) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.sessions.expire(maxAge)
+	return nil
+}
+
+// ------------------------------------------------------------
+
+func (sessions SessionMap) get(token string) *Session {
+	session := sessions[token]
+	if session == nil {
+		return nil
+	}
+	clone := *session
+	return &clone
+}
+
+func (sessions SessionMap) put(token string, session *Session) {
+	clone := *session
+	sessions[token] = &clone
+}
+
+// touch reports whether the session exists.
+func (sessions SessionMap) touch(token string, refreshTime time.Time) bool {
+	session := sessions[token]
+	if session == nil {
+		return false
+	}
+	session.RefreshTime = refreshTime
+	return true
+}
+
+func (sessions SessionMap) copy() SessionMap {
+	clone := make(SessionMap, len(sessions))
+	for token := range sessions {
+		if session := sessions.get(token); session != nil {
+			clone[token] = session
+		}
+	}
+	return clone
+}
+
+// expired returns the tokens of the sessions not refreshed within maxAge.
+func (sessions SessionMap) expired(maxAge time.Duration) []string {
+	var expired []string
+	for token, session := range sessions {
+		if session == nil || time.Since(session.RefreshTime) > maxAge {
+			expired = append(expired, token)
+		}
+	}
+	return expired
+}
+
+// expire returns the tokens of the deleted sessions.
+func (sessions SessionMap) expire(maxAge time.Duration) []string {
+	expired := sessions.expired(maxAge)
+	for _, token := range expired {
+		delete(sessions, token)
+	}
+	return expired
+}
+
+// ------------------------------------------------------------
+
+// controllerSessionStore adapts the LoadSessions and RefreshSession methods of
+// a ServerController into a SessionStore. The sessions are kept in memory, and
+// RefreshSession is called with the whole map after every change.
+type controllerSessionStore struct {
+	mutex    sync.Mutex
+	ctrl     ServerController
+	sessions SessionMap
+}
+
+func newControllerSessionStore(ctrl ServerController) *controllerSessionStore {
+	store := &controllerSessionStore{
+		ctrl:     ctrl,
+		sessions: SessionMap{},
+	}
+	ctrl.LoadSessions(store.sessions)
+	return store
+}
+
+func (store *controllerSessionStore) Get(token string) (*Session, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if session, ok := store.sessions[token]; ok && session == nil {
+		// SessionMap contains nil entry. Make noise because this needs to be
+		// tracked down.
+		sessionLogger.ERROR(`http.Server.getOpenSession: "sessions" had nil entry: %s`, token)
+
+		// Delete invalid session entry
+		delete(store.sessions, token)
+		store.ctrl.RefreshSession(token, store.sessions)
+	}
+	return store.sessions.get(token), nil
+}
+
+func (store *controllerSessionStore) Put(token string, session *Session) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.sessions.put(token, session)
+	store.ctrl.RefreshSession(token, store.sessions)
+	return nil
+}
+
+func (store *controllerSessionStore) Delete(token string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.sessions, token)
+	store.ctrl.RefreshSession(token, store.sessions)
+	return nil
+}
+
+func (store *controllerSessionStore) Touch(token string, refreshTime time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.sessions.touch(token, refreshTime) {
+		store.ctrl.RefreshSession(token, store.sessions)
+	}
+	return nil
+}
+
+func (store *controllerSessionStore) List() (SessionMap, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.sessions.copy(), nil
+}
+
+func (store *controllerSessionStore) Expire(maxAge time.Duration) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for _, token := range store.sessions.expire(maxAge) {
+		store.ctrl.RefreshSession(token, store.sessions)
+	}
+	return nil
+}
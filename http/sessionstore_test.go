@@ -0,0 +1,146 @@
+package http
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func lookupTestUser(username string) User {
+	if username == "nobody" {
+		return nil
+	}
+	return testUser(username)
+}
+
+// exerciseSessionStore runs the same changes on every store.
+func exerciseSessionStore(t *testing.T, store SessionStore) {
+	now := time.Now()
+	store.Put("a", &Session{User: testUser("alice"), RefreshTime: now})
+	store.Put("b", &Session{User: testUser("bob"), RefreshTime: now.Add(-2 * time.Hour)})
+	store.Put("c", &Session{User: testUser("carol"), RefreshTime: now})
+	store.Put("n", &Session{User: testUser("nobody"), RefreshTime: now})
+	store.Touch("c", now.Add(-3*time.Hour))
+	store.Touch("missing", now)
+	store.Delete("a")
+	if err := store.Expire(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	store.Put("d", &Session{User: testUser("dave"), RefreshTime: now})
+	store.Touch("d", now.Add(time.Minute))
+}
+
+func verifySessionStore(t *testing.T, name string, store SessionStore, nobody bool) {
+	sessions, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := 1
+	if nobody {
+		want = 2
+	}
+	if len(sessions) != want {
+		t.Errorf("%s: want %d sessions, got %d", name, want, len(sessions))
+	}
+	d, _ := store.Get("d")
+	if d == nil || d.User.Username() != "dave" || !d.RefreshTime.Equal(sessions["d"].RefreshTime) {
+		t.Errorf("%s: session d is not right: %+v", name, d)
+	}
+	if a, _ := store.Get("a"); a != nil {
+		t.Errorf("%s: deleted session still exists", name)
+	}
+}
+
+func Test_SessionStores(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "sessions.json")
+	logFile := filepath.Join(dir, "sessions.log")
+
+	jsonStore, err := NewJSONFileSessionStore(jsonFile, lookupTestUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logStore, err := NewLogSessionStore(logFile, lookupTestUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data = []struct {
+		name  string
+		store SessionStore
+	}{
+		{"memory", NewMemorySessionStore()},
+		{"controller", newControllerSessionStore(&testController{})},
+		{"json", jsonStore},
+		{"log", logStore},
+	}
+
+	for _, d := range data {
+		exerciseSessionStore(t, d.store)
+		verifySessionStore(t, d.name, d.store, true)
+	}
+
+	// Verify: the file stores come back the same, apart from the sessions
+	// lookupTestUser doesn't know.
+	if err := jsonStore.Close(); err != nil {
+		t.Fatal(err)
+	}
+	logStore.Close()
+	if jsonStore, err = NewJSONFileSessionStore(jsonFile, lookupTestUser); err != nil {
+		t.Fatal(err)
+	}
+	verifySessionStore(t, "json reopened", jsonStore, false)
+
+	if logStore, err = NewLogSessionStore(logFile, lookupTestUser); err != nil {
+		t.Fatal(err)
+	}
+	defer logStore.Close()
+	verifySessionStore(t, "log reopened", logStore, false)
+}
+
+func Test_LogSessionStoreCompaction(t *testing.T) {
+	// Setup
+	store, err := NewLogSessionStore(filepath.Join(t.TempDir(), "sessions.log"), lookupTestUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	store.Put("a", &Session{User: testUser("alice"), RefreshTime: time.Now()})
+
+	// Test object
+	for i := 0; i < 2*logCompactSlack; i++ {
+		if err := store.Touch("a", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Verify
+	if store.lines > logCompactSlack {
+		t.Errorf("Log was not compacted: %d lines", store.lines)
+	}
+}
+
+func Test_LogSessionStoreFailure(t *testing.T) {
+	// Setup
+	store, err := NewLogSessionStore(filepath.Join(t.TempDir(), "sessions.log"), lookupTestUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Put("a", &Session{User: testUser("alice"), RefreshTime: time.Now().Add(-2 * time.Hour)})
+	store.Put("b", &Session{User: testUser("bob"), RefreshTime: time.Now().Add(-2 * time.Hour)})
+	store.Close()
+
+	// Test object
+	putErr := store.Put("c", &Session{User: testUser("carol"), RefreshTime: time.Now()})
+	expireErr := store.Expire(time.Hour)
+
+	// Verify: the changes that couldn't be logged weren't made.
+	if putErr == nil || expireErr == nil {
+		t.Errorf("Changes to a closed log succeeded")
+	}
+	sessions, _ := store.List()
+	if len(sessions) != 2 || sessions["a"] == nil || sessions["b"] == nil {
+		t.Errorf("Unexpected sessions %v", sessions)
+	}
+}
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ShutdownTimeout limits how long Run waits for requests to finish.
+var ShutdownTimeout = 30 * time.Second
+
+// Shutdown stops the server gracefully. It stops accepting connections, waits
+// for the requests being served to finish, and then for the handlers of
+// WebSocketResolutions, whose request contexts are canceled as a signal to
+// close. Finally, it closes the session store if it is an io.Closer, so that
+// a store can save what it has pending.
+//
+// If ctx ends first, Shutdown returns its error without closing the store.
+func (srv *Server) Shutdown(ctx context.Context) error {
+	logger.INFO("Shutting down HTTP server at %s", srv.httpServer.Addr)
+
+	if err := srv.httpServer.Shutdown(ctx); err != nil {
+		return err
+	}
+	srv.cancelBase()
+	if err := srv.webSockets.wait(ctx); err != nil {
+		return err
+	}
+
+	if closer, ok := srv.sessions.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Run serves HTTP until ctx ends or the process gets SIGINT or SIGTERM, and
+// then shuts the server down, waiting at most ShutdownTimeout.
+func (srv *Server) Run(ctx context.Context) error {
+	return srv.run(ctx, srv.Start)
+}
+
+// RunTLS is like Run, for HTTPS.
+func (srv *Server) RunTLS(ctx context.Context, certFile, keyFile string) error {
+	return srv.run(ctx, func() error {
+		return srv.StartTLS(certFile, keyFile)
+	})
+}
+
+func (srv *Server) run(ctx context.Context, start func() error) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	started := make(chan error, 1)
+	go func() {
+		started <- start()
+	}()
+
+	select {
+	case err := <-started:
+		// Failed to start, or shut down by someone else.
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	return errors.Join(srv.Shutdown(shutdownCtx), <-started)
+}
+
+// ------------------------------------------------------------
+
+// activeCounter counts running operations, and lets one wait for them.
+type activeCounter struct {
+	mutex sync.Mutex
+	count int
+	idle  chan struct{}
+}
+
+func (c *activeCounter) add() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.count++
+}
+
+func (c *activeCounter) done() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.count--; c.count == 0 && c.idle != nil {
+		close(c.idle)
+		c.idle = nil
+	}
+}
+
+func (c *activeCounter) wait(ctx context.Context) error {
+	c.mutex.Lock()
+	if c.count == 0 {
+		c.mutex.Unlock()
+		return nil
+	}
+	if c.idle == nil {
+		c.idle = make(chan struct{})
+	}
+	idle := c.idle
+	c.mutex.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"context"
+	"net"
+	go_http "net/http"
+	"testing"
+	"time"
+)
+
+type closingStore struct {
+	*MemorySessionStore
+	closed bool
+}
+
+func (store *closingStore) Close() error {
+	store.closed = true
+	return nil
+}
+
+type baseContextKey struct{}
+
+type shutdownController struct {
+	testController
+	store *closingStore
+}
+
+func (ctrl *shutdownController) SessionStore() SessionStore { return ctrl.store }
+
+func (ctrl *shutdownController) ConfigureHttpServer(srv *go_http.Server) {
+	srv.BaseContext = func(net.Listener) context.Context {
+		return context.WithValue(context.Background(), baseContextKey{}, "base")
+	}
+}
+
+func Test_ServerShutdown(t *testing.T) {
+	// Setup
+	started := make(chan struct{})
+	closed := false
+	var baseValue any
+	ctrl := &shutdownController{store: &closingStore{MemorySessionStore: NewMemorySessionStore()}}
+	ctrl.handler = func(req *go_http.Request, urlParts []string, user User) Resolution {
+		return &WebSocketResolution{
+			Handler: func(out go_http.ResponseWriter, req *go_http.Request) bool {
+				conn, _, err := out.(go_http.Hijacker).Hijack()
+				if err != nil {
+					return false
+				}
+				defer conn.Close()
+
+				baseValue = req.Context().Value(baseContextKey{})
+				close(started)
+				<-req.Context().Done()
+				closed = true
+				return true
+			},
+		}
+	}
+	srv := NewServer(ctrl)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(listener)
+	go go_http.Get("http://" + listener.Addr().String() + "/socket")
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WebSocket handler did not start")
+	}
+
+	// Test object
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = srv.Shutdown(ctx)
+
+	// Verify
+	if err != nil {
+		t.Errorf("Shutdown failed: %s", err)
+	}
+	if !closed {
+		t.Errorf("Shutdown did not wait for the WebSocket handler")
+	}
+	if baseValue != "base" {
+		t.Errorf("Request context does not derive from the BaseContext of the controller")
+	}
+	if !ctrl.store.closed {
+		t.Errorf("Session store was not closed")
+	}
+}
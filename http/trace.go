@@ -0,0 +1,27 @@
+package http
+
+import (
+	go_http "net/http"
+	"strings"
+
+	"github.com/pjsaksa/go-utils/log"
+)
+
+// requestTrace continues the trace of an incoming request with a new span,
+// or starts a new trace if the request has no valid "traceparent" header.
+func requestTrace(req *go_http.Request) log.TraceContext {
+	if tc, ok := log.ParseTraceparent(req.Header.Get("traceparent")); ok {
+		tc.State = strings.Join(req.Header.Values("tracestate"), ",")
+		return tc.Child()
+	}
+	return log.NewTraceContext()
+}
+
+// TraceFromRequest returns the trace context Server assigned to req. Use
+// Child() and Traceparent() on it to propagate the trace to outgoing requests.
+// Messages logged through log.FromContext(req.Context()), or through a
+// component logger's ForContext, carry the trace id.
+func TraceFromRequest(req *go_http.Request) log.TraceContext {
+	tc, _ := log.TraceFromContext(req.Context())
+	return tc
+}
@@ -46,7 +46,7 @@ func Test_SplitUrlPath(t *testing.T) {
 	}
 
 	for i := range data {
-		if output := splitUrlPath(data[i].input); !compareUrlParts(output, data[i].want) {
+		if output, _ := splitUrlPath(data[i].input); !compareUrlParts(output, data[i].want) {
 			t.Errorf("FAIL: \"%s\"\n-> %q\n!= %q",
 				data[i].input,
 				output,
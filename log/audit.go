@@ -0,0 +1,204 @@
+package log
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditSink appends EVENT records, and records that chain an EVENT message, to
+// a hash-chained audit file. Level thresholds never drop EVENT messages, so
+// every one reaches the sink. Each line is
+//
+//	<hash> <json>
+//
+// where the JSON payload contains a sequence number and the hash of the
+// previous line, and <hash> is the SHA-256 (or, with a key, HMAC-SHA-256) of
+// the payload. Modifying, deleting or reordering lines breaks the chain,
+// which VerifyAudit detects. Cutting lines from the end of the file can only
+// be detected by comparing against a previously recorded last hash.
+//
+// Every audited record costs a write and an fsync of the audit file, and no
+// level threshold can turn it off. A chain with an EVENT message anywhere,
+// like Chain(EventMsg("HTTP Request")).Add(WarningMsg("404")), is audited
+// every time it is written; keep EVENT for what needs an audit trail and log
+// per-request records at INFO.
+type AuditSink struct {
+	mutex sync.Mutex
+	file  *os.File
+	key   []byte
+	seq   uint64
+	prev  string
+	err   error
+}
+
+type auditEntry struct {
+	Seq       uint64         `json:"seq"`
+	Time      string         `json:"time"`
+	Component string         `json:"component,omitempty"`
+	Message   string         `json:"message"`
+	Fields    map[string]any `json:"fields,omitempty"`
+	Prev      string         `json:"prev"`
+}
+
+// AuditSummary describes a verified audit file.
+type AuditSummary struct {
+	Records  uint64
+	LastHash string
+}
+
+// AuditError tells which line of an audit file failed verification.
+type AuditError struct {
+	Line   int
+	Reason string
+}
+
+func (err *AuditError) Error() string {
+	return fmt.Sprintf("audit line %d: %s", err.Line, err.Reason)
+}
+
+var auditGenesis = strings.Repeat("0", 2*sha256.Size)
+
+// NewAuditSink opens (or creates) an audit file. An existing file is
+// verified first, and new records continue its chain.
+func NewAuditSink(fileName string, key []byte) (*AuditSink, error) {
+	file, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := VerifyAudit(file, key)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("log.NewAuditSink: %s: %w", fileName, err)
+	}
+
+	return &AuditSink{
+		file: file,
+		key:  key,
+		seq:  summary.Records,
+		prev: summary.LastHash,
+	}, nil
+}
+
+// Write appends r to the audit file if it chains an EVENT message. The file is
+// synced after every record.
+func (sink *AuditSink) Write(r *Record) {
+	if !hasEvent(r.Messages) {
+		return
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	if sink.file == nil || sink.err != nil {
+		return
+	}
+
+	entry := auditEntry{
+		Seq:       sink.seq + 1,
+		Time:      r.Time.Format(time.RFC3339Nano),
+		Component: r.Component(),
+		Prev:      sink.prev,
+	}
+	texts := make([]string, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		texts = append(texts, m.Text())
+	}
+	entry.Message = strings.Join(texts, chainSeparator)
+	if fields := r.Fields(); len(fields) > 0 {
+		entry.Fields = make(map[string]any, len(fields))
+		for _, f := range fields {
+			entry.Fields[f.Key] = jsonValue(f.Value)
+		}
+	}
+
+	payload, _ := json.Marshal(&entry)
+	sum := auditHash(sink.key, payload)
+
+	if _, err := io.WriteString(sink.file, sum+" "+string(payload)+"\n"); err != nil {
+		sink.err = err
+		return
+	}
+	if err := sink.file.Sync(); err != nil {
+		sink.err = err
+		return
+	}
+
+	sink.seq = entry.Seq
+	sink.prev = sum
+}
+
+// Close closes the audit file and returns the first write error, if any.
+func (sink *AuditSink) Close() error {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	if sink.file == nil {
+		return sink.err
+	}
+	if err := sink.file.Close(); err != nil && sink.err == nil {
+		sink.err = err
+	}
+	sink.file = nil
+	return sink.err
+}
+
+// ------------------------------------------------------------
+
+// VerifyAudit checks the hash chain of an audit file. The returned summary
+// can be stored elsewhere to detect later truncation of the file.
+func VerifyAudit(in io.Reader, key []byte) (AuditSummary, error) {
+	summary := AuditSummary{LastHash: auditGenesis}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 16<<20)
+
+	for line := 1; scanner.Scan(); line++ {
+		sum, payload, found := strings.Cut(scanner.Text(), " ")
+		if !found {
+			return summary, &AuditError{Line: line, Reason: "malformed line"}
+		}
+		if !hmac.Equal([]byte(sum), []byte(auditHash(key, []byte(payload)))) {
+			return summary, &AuditError{Line: line, Reason: "hash mismatch, line was modified"}
+		}
+
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(payload), &entry); err != nil {
+			return summary, &AuditError{Line: line, Reason: "invalid JSON: " + err.Error()}
+		}
+		if entry.Seq != summary.Records+1 {
+			return summary, &AuditError{
+				Line:   line,
+				Reason: fmt.Sprintf("sequence %d follows %d, lines were deleted or reordered", entry.Seq, summary.Records),
+			}
+		}
+		if entry.Prev != summary.LastHash {
+			return summary, &AuditError{Line: line, Reason: "chain broken, previous line was replaced"}
+		}
+
+		summary.Records = entry.Seq
+		summary.LastHash = sum
+	}
+	return summary, scanner.Err()
+}
+
+func auditHash(key []byte, payload []byte) string {
+	var h hash.Hash
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAudit_Chain(t *testing.T) {
+	// Setup
+	fileName := filepath.Join(t.TempDir(), "audit.log")
+	key := []byte("secret")
+
+	writeEvents := func(messages ...string) {
+		sink, err := NewAuditSink(fileName, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sink.Close()
+
+		for _, m := range messages {
+			sink.Write(&Record{Time: time.Now(), Messages: []Message{EventMsg("%s", m)}})
+			sink.Write(&Record{Time: time.Now(), Messages: []Message{InfoMsg("not audited")}})
+		}
+	}
+
+	// Test object: reopening the file continues the chain.
+	writeEvents("sign-in alice", "sign-out alice")
+	writeEvents("sign-in bob")
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := VerifyAudit(strings.NewReader(string(data)), key)
+	if err != nil || summary.Records != 3 {
+		t.Fatalf("Verification of intact file failed: %+v, %v", summary, err)
+	}
+
+	// Verify that tampering is detected.
+	lines := strings.SplitAfter(string(data), "\n")
+	var data_tampered = []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{
+			name:  "modified",
+			input: lines[0] + strings.Replace(lines[1], "alice", "mallory", 1) + lines[2],
+			line:  2,
+		}, {
+			name:  "deleted",
+			input: lines[0] + lines[2],
+			line:  2,
+		}, {
+			name:  "reordered",
+			input: lines[1] + lines[0] + lines[2],
+			line:  1,
+		}, {
+			name:  "wrong key",
+			input: string(data),
+			line:  1,
+		},
+	}
+
+	for i := range data_tampered {
+		verifyKey := key
+		if data_tampered[i].name == "wrong key" {
+			verifyKey = []byte("guess")
+		}
+
+		_, err := VerifyAudit(strings.NewReader(data_tampered[i].input), verifyKey)
+
+		var auditErr *AuditError
+		if !errors.As(err, &auditErr) || auditErr.Line != data_tampered[i].line {
+			t.Errorf("FAIL: %s: got %v, want error on line %d",
+				data_tampered[i].name,
+				err,
+				data_tampered[i].line)
+		}
+	}
+}
+
+func TestAudit_Level(t *testing.T) {
+	// Setup
+	fileName := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewAuditSink(fileName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remove := AddSink(sink)
+	SetOutput(nil)
+
+	// Cleanup
+	defer func() {
+		remove()
+		sink.Close()
+		ResetOutput()
+		SetLevel(LevelDebug)
+	}()
+
+	// Test object
+	SetLevel(LevelError)
+	EVENT("sign-in alice")
+	WARNING("not audited")
+	chain := Chain(EventMsg("sign-out alice"))
+	chain.Add(WarningMsg("session expired"))
+	chain.Write()
+	SetLevel(LevelFatal)
+	Named("test").EVENT("sign-in bob")
+
+	// Verify output.
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := VerifyAudit(strings.NewReader(string(data)), nil)
+	if err != nil || summary.Records != 3 {
+		t.Errorf("FAIL: got %d records (%v), want 3:\n%s", summary.Records, err, data)
+	}
+}
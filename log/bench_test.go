@@ -0,0 +1,125 @@
+package log
+
+import (
+	"io"
+	"testing"
+)
+
+// Boxing a non-constant argument into an interface allocates in the caller,
+// before the level is checked, so the tests and benchmarks of disabled calls
+// pass their arguments boxed already.
+var boxedArg any = 1234
+
+func TestAllocs_Disabled(t *testing.T) {
+	// Setup
+	SetOutput(io.Discard)
+	SetLevel(LevelError)
+	component := Named("alloc").With("user", "admin")
+	chain := []Message{InfoMsg("GET /index.html"), DebugMsg("%d bytes", 1234)}
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		SetLevel(LevelDebug)
+	}()
+
+	data := []struct {
+		name string
+		call func()
+	}{
+		{"DEBUG", func() { DEBUG("request %d served", boxedArg) }},
+		{"INFO", func() { INFO("request %d served", boxedArg) }},
+		{"WARNING", func() { WARNING("request %d served", boxedArg) }},
+		{"Logger.DEBUG", func() { component.DEBUG("request %d served", boxedArg) }},
+		{"Logger.INFO", func() { component.INFO("request %d served", boxedArg) }},
+		{"LOG", func() { LOG(chain...) }},
+		{"Chain", func() {
+			chain := Chain(InfoMsg("GET /index.html"))
+			chain.Add(DebugMsg("%d bytes", boxedArg))
+			chain.Add(component.WarningMsg("slow: %s", "12ms"))
+			chain.Write()
+		}},
+	}
+
+	for _, d := range data {
+		allocs := testing.AllocsPerRun(100, d.call)
+		if allocs != 0 {
+			t.Errorf("FAIL: disabled %s: %v allocations per call", d.name, allocs)
+		}
+	}
+}
+
+func BenchmarkDEBUG_Disabled(b *testing.B) {
+	SetOutput(io.Discard)
+	SetLevel(LevelInfo)
+	defer func() {
+		ResetOutput()
+		SetLevel(LevelDebug)
+	}()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		DEBUG("request %d served", boxedArg)
+	}
+}
+
+func BenchmarkDEBUG(b *testing.B) {
+	SetOutput(io.Discard)
+	defer ResetOutput()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		DEBUG("request served")
+	}
+}
+
+func BenchmarkINFO_Args(b *testing.B) {
+	SetOutput(io.Discard)
+	defer ResetOutput()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		INFO("request %d served in %s", i, "12ms")
+	}
+}
+
+func BenchmarkERROR_JSON(b *testing.B) {
+	SetOutput(io.Discard)
+	SetFormat(FormatJSON)
+	defer func() {
+		ResetOutput()
+		ResetFormat()
+	}()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ERROR("request %d failed", i)
+	}
+}
+
+func BenchmarkChain(b *testing.B) {
+	SetOutput(io.Discard)
+	defer ResetOutput()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		chain := Chain(EventMsg("GET /index.html"))
+		chain.Add(DebugMsg("%d bytes", 1234))
+		chain.Write()
+	}
+}
+
+func BenchmarkChain_Disabled(b *testing.B) {
+	SetOutput(io.Discard)
+	SetLevel(LevelWarning)
+	defer func() {
+		ResetOutput()
+		SetLevel(LevelDebug)
+	}()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		chain := Chain(InfoMsg("GET /index.html"))
+		chain.Add(DebugMsg("%d bytes", boxedArg))
+		chain.Write()
+	}
+}
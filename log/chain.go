@@ -1,20 +1,54 @@
 package log
 
+import (
+	"time"
+)
+
+// chainData keeps the first messages of a chain inline, so that a chain that
+// doesn't pass the level threshold costs no allocations.
 type chainData struct {
-	messages []Message
+	inline [chainInline]Message
+	n      int
+	more   []Message // all messages, once there are more than chainInline
 }
 
+const chainInline = 4
+
 func Chain(msg Message) *chainData {
-	return &chainData{
-		messages: []Message{msg},
-	}
+	chain := &chainData{n: 1}
+	chain.inline[0] = msg
+	return chain
 }
 
 func (chain *chainData) Add(msg Message) {
-	chain.messages = append(chain.messages, msg)
+	switch {
+	case chain.more != nil:
+		chain.more = append(chain.more, msg)
+	case chain.n < chainInline:
+		chain.inline[chain.n] = msg
+		chain.n++
+	default:
+		chain.more = append(append(make([]Message, 0, 2*chainInline), chain.inline[:]...), msg)
+	}
 }
 
 func (chain *chainData) Write() {
-	LOG(chain.messages...)
-	chain.messages = nil
+	if chain.more != nil {
+		LOG(chain.more...)
+	} else if chain.n > 0 {
+		LOG(chain.inline[:chain.n]...)
+	} else {
+		LOG()
+	}
+	*chain = chainData{}
+}
+
+// Timed starts timing a step. The returned function adds "<name> took
+// <elapsed>" to the chain as a DEBUG message, or as a WARNING if the step took
+// longer than warnAfter (if warnAfter is positive).
+func (chain *chainData) Timed(name string, warnAfter time.Duration) (done func()) {
+	start := time.Now()
+	return func() {
+		chain.Add(timedMsg(LevelDebug, warnAfter, name, time.Since(start)))
+	}
 }
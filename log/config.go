@@ -0,0 +1,534 @@
+package log
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ConfigureFromEnv configures logging from environment variables:
+//
+//	LOG_CONFIG  configuration file, applied first (see Configure)
+//	LOG_LEVEL   global level, optionally followed by component levels,
+//	            e.g. "info,http=debug"
+//	LOG_FORMAT  format of the main output: text, json, gelf or otel
+//	LOG_COLOR   true, false or auto (color only if writing to a terminal)
+//	LOG_THEME   color theme: default, light, 256 or truecolor
+//	LOG_FILE    write the main output to this file instead of stderr
+//
+// Unset variables leave the corresponding setting alone. Everything is
+// validated before anything is applied, so an error leaves the settings as
+// they were. The file of an earlier LOG_FILE is closed once it is replaced.
+func ConfigureFromEnv() error {
+	var cfg *config
+	var newSinks []Sink
+	if fileName := os.Getenv("LOG_CONFIG"); len(fileName) > 0 {
+		var err error
+		if cfg, newSinks, err = loadConfigFile(fileName); err != nil {
+			return err
+		}
+	}
+
+	env, err := parseEnv()
+	if err != nil {
+		closeSinks(newSinks)
+		return err
+	}
+
+	configured.Lock()
+	defer configured.Unlock()
+
+	if cfg != nil {
+		cfg.apply(newSinks)
+	}
+	env.apply()
+	return nil
+}
+
+// envConfig holds the validated settings of the environment variables.
+type envConfig struct {
+	levels []levelSetting
+	format *Format
+	color  string
+	theme  *Theme
+	file   *os.File
+}
+
+func parseEnv() (*envConfig, error) {
+	env := &envConfig{color: os.Getenv("LOG_COLOR")}
+
+	if value := os.Getenv("LOG_LEVEL"); len(value) > 0 {
+		levels, err := parseLevels(value)
+		if err != nil {
+			return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+		}
+		env.levels = levels
+	}
+
+	if value := os.Getenv("LOG_FORMAT"); len(value) > 0 {
+		format, err := ParseFormat(value)
+		if err != nil {
+			return nil, fmt.Errorf("LOG_FORMAT: %w", err)
+		}
+		env.format = &format
+	}
+
+	if len(env.color) > 0 {
+		if _, err := parseColor(env.color, nil); err != nil {
+			return nil, fmt.Errorf("LOG_COLOR: %w", err)
+		}
+	}
+
+	if value := os.Getenv("LOG_THEME"); len(value) > 0 {
+		theme, err := ParseTheme(value)
+		if err != nil {
+			return nil, fmt.Errorf("LOG_THEME: %w", err)
+		}
+		env.theme = &theme
+	}
+
+	// Opened last, as nothing can fail after it.
+	if fileName := os.Getenv("LOG_FILE"); len(fileName) > 0 {
+		file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("LOG_FILE: %w", err)
+		}
+		env.file = file
+	}
+	return env, nil
+}
+
+// apply must be called with configured locked.
+func (env *envConfig) apply() {
+	applyLevels(env.levels)
+	if env.theme != nil {
+		SetTheme(*env.theme)
+	}
+
+	changeOutput(func(s *outputSettings) {
+		if env.format != nil {
+			s.format = *env.format
+		}
+		if env.file != nil {
+			s.w = env.file
+			s.color = false
+		}
+		if len(env.color) > 0 {
+			s.color, _ = parseColor(env.color, s.w)
+		}
+	})
+
+	if env.file != nil {
+		replaceConfiguredFile(env.file)
+	}
+}
+
+// replaceConfiguredFile must be called with configured locked.
+func replaceConfiguredFile(file *os.File) {
+	if configured.file != nil {
+		configured.file.Close()
+	}
+	configured.file = file
+}
+
+type levelSetting struct {
+	component string
+	level     Level
+}
+
+// parseLevels parses "level[,component=level...]".
+func parseLevels(value string) ([]levelSetting, error) {
+	var levels []levelSetting
+	for _, item := range strings.Split(value, ",") {
+		component, levelName, found := strings.Cut(item, "=")
+		if !found {
+			component, levelName = "", item
+		}
+		level, err := ParseLevel(levelName)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, levelSetting{strings.TrimSpace(component), level})
+	}
+	return levels, nil
+}
+
+func applyLevels(levels []levelSetting) {
+	for _, ls := range levels {
+		loggerFor(ls.component).SetLevel(ls.level)
+	}
+}
+
+func parseColor(value string, output io.Writer) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "auto":
+		return isTerminal(output), nil
+	default:
+		return strconv.ParseBool(value)
+	}
+}
+
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ------------------------------------------------------------
+
+func ConfigureFromFile(fileName string) error {
+	cfg, newSinks, err := loadConfigFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	configured.Lock()
+	defer configured.Unlock()
+
+	cfg.apply(newSinks)
+	return nil
+}
+
+func loadConfigFile(fileName string) (*config, []Sink, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	cfg, newSinks, err := loadConfig(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return cfg, newSinks, nil
+}
+
+// Configure reads a logging configuration:
+//
+//	# Global settings
+//	level = info
+//	level.http = debug
+//	max_message_length = 65536
+//	theme = light        # default, light, 256 or truecolor
+//
+//	[sink]
+//	type = file          # stderr, stdout, file, tcp, gelf-udp or audit
+//	path = /var/log/app.log
+//	format = json        # text, json, gelf or otel
+//	color = false        # true, false or auto
+//	level = warning
+//	max_size = 10M       # rotate after this size (K, M or G suffix)
+//	max_files = 5        # keep this many rotated files
+//
+//	[sink]
+//	type = tcp
+//	address = collector:5170
+//	spool = /var/spool/app/log
+//	max_spool = 100M     # drop records beyond this spool size
+//	framing = json-lines # or length-prefixed
+//
+// Every [sink] section adds a sink ("tcp" and "gelf-udp" use address, "audit"
+// uses path and an optional key_file). If any sinks are configured, the main
+// output is turned off. Sinks of an earlier Configure call are closed.
+//
+// A "#" starts a comment at the start of a line or after whitespace; elsewhere
+// it is part of the value. The whole configuration is validated, and the sinks
+// created, before anything is applied.
+func Configure(in io.Reader) error {
+	cfg, newSinks, err := loadConfig(in)
+	if err != nil {
+		return err
+	}
+
+	configured.Lock()
+	defer configured.Unlock()
+
+	cfg.apply(newSinks)
+	return nil
+}
+
+// loadConfig parses a configuration and builds its sinks.
+func loadConfig(in io.Reader) (*config, []Sink, error) {
+	cfg, err := parseConfig(in)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newSinks := make([]Sink, 0, len(cfg.sinks))
+	for _, sc := range cfg.sinks {
+		sink, err := sc.build()
+		if err != nil {
+			closeSinks(newSinks)
+			return nil, nil, fmt.Errorf("line %d: %w", sc.line, err)
+		}
+		newSinks = append(newSinks, sink)
+	}
+	return cfg, newSinks, nil
+}
+
+func closeSinks(list []Sink) {
+	for _, s := range list {
+		s.Close()
+	}
+}
+
+// configured holds what the configuration functions set up. It is locked
+// while a configuration is applied, so that concurrent calls don't mix.
+var configured struct {
+	sync.Mutex
+	sinks   []Sink
+	removes []func()
+	file    *os.File // of LOG_FILE
+}
+
+// apply must be called with configured locked.
+func (cfg *config) apply(newSinks []Sink) {
+	applyLevels(cfg.levels)
+	if cfg.maxMessageLength != nil {
+		SetMaxMessageLength(*cfg.maxMessageLength)
+	}
+	if cfg.theme != nil {
+		SetTheme(*cfg.theme)
+	}
+
+	replaceConfiguredSinks(newSinks)
+	if len(newSinks) > 0 {
+		SetOutput(nil)
+		replaceConfiguredFile(nil)
+	}
+}
+
+// replaceConfiguredSinks must be called with configured locked.
+func replaceConfiguredSinks(newSinks []Sink) {
+	for idx, s := range configured.sinks {
+		configured.removes[idx]()
+		s.Close()
+	}
+
+	configured.sinks = newSinks
+	configured.removes = make([]func(), 0, len(newSinks))
+	for _, s := range newSinks {
+		configured.removes = append(configured.removes, AddSink(s))
+	}
+}
+
+// ------------------------------------------------------------
+
+type config struct {
+	levels           []levelSetting
+	maxMessageLength *int
+	theme            *Theme
+	sinks            []*sinkConfig
+}
+
+type sinkConfig struct {
+	line   int
+	values map[string]string
+}
+
+func parseConfig(in io.Reader) (*config, error) {
+	cfg := &config{}
+	var current *sinkConfig
+
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(stripComment(scanner.Text()))
+
+		switch {
+		case len(text) == 0:
+			continue
+		case text == "[sink]":
+			current = &sinkConfig{line: line, values: map[string]string{}}
+			cfg.sinks = append(cfg.sinks, current)
+			continue
+		}
+
+		key, value, found := strings.Cut(text, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected 'key = value'", line)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		if current != nil {
+			current.values[key] = value
+			continue
+		}
+
+		if err := cfg.parseGlobal(key, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return cfg, scanner.Err()
+}
+
+// stripComment cuts a comment off line. "#" starts one at the start of the
+// line or after whitespace, so values like "#fff" or "a#b" are kept whole.
+func stripComment(line string) string {
+	for idx := 0; idx < len(line); idx++ {
+		if line[idx] == '#' && (idx == 0 || line[idx-1] == ' ' || line[idx-1] == '\t') {
+			return line[:idx]
+		}
+	}
+	return line
+}
+
+func (cfg *config) parseGlobal(key, value string) error {
+	switch {
+	case key == "level" || strings.HasPrefix(key, "level."):
+		level, err := ParseLevel(value)
+		if err != nil {
+			return err
+		}
+		component := strings.TrimPrefix(strings.TrimPrefix(key, "level"), ".")
+		cfg.levels = append(cfg.levels, levelSetting{component, level})
+	case key == "max_message_length":
+		max, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		cfg.maxMessageLength = &max
+	case key == "theme":
+		theme, err := ParseTheme(value)
+		if err != nil {
+			return err
+		}
+		cfg.theme = &theme
+	default:
+		return fmt.Errorf("unknown setting '%s'", key)
+	}
+	return nil
+}
+
+func (sc *sinkConfig) build() (Sink, error) {
+	format, err := ParseFormat(sc.get("format", "text"))
+	if err != nil {
+		return nil, err
+	}
+	level, err := ParseLevel(sc.get("level", "debug"))
+	if err != nil {
+		return nil, err
+	}
+
+	switch sc.get("type", "") {
+	case "stderr":
+		return sc.writerSink(stdStream{os.Stderr}, format, level)
+	case "stdout":
+		return sc.writerSink(stdStream{os.Stdout}, format, level)
+	case "file":
+		maxSize, err := parseSize(sc.get("max_size", "0"))
+		if err != nil {
+			return nil, err
+		}
+		maxFiles, err := strconv.Atoi(sc.get("max_files", "0"))
+		if err != nil {
+			return nil, err
+		}
+		file, err := NewRotatingFile(sc.get("path", ""), maxSize, maxFiles)
+		if err != nil {
+			return nil, err
+		}
+		return sc.writerSink(file, format, level)
+	case "tcp":
+		framing := FramingJSONLines
+		switch sc.get("framing", "json-lines") {
+		case "json-lines":
+		case "length-prefixed":
+			framing = FramingLengthPrefixed
+		default:
+			return nil, fmt.Errorf("unknown framing '%s'", sc.get("framing", ""))
+		}
+		maxSpool, err := parseSize(sc.get("max_spool", "0"))
+		if err != nil {
+			return nil, err
+		}
+		sink, err := NewTCPSink(TCPSinkConfig{
+			Address:   sc.get("address", ""),
+			Framing:   framing,
+			SpoolFile: sc.get("spool", ""),
+			MaxSpool:  maxSpool,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &levelSink{Sink: sink, level: level}, nil
+	case "gelf-udp":
+		sink, err := NewGELFUDPSink(sc.get("address", ""))
+		if err != nil {
+			return nil, err
+		}
+		return &levelSink{Sink: sink, level: level}, nil
+	case "audit":
+		var key []byte
+		if keyFile := sc.get("key_file", ""); len(keyFile) > 0 {
+			if key, err = os.ReadFile(keyFile); err != nil {
+				return nil, err
+			}
+		}
+		return NewAuditSink(sc.get("path", ""), key)
+	default:
+		return nil, fmt.Errorf("unknown sink type '%s'", sc.get("type", ""))
+	}
+}
+
+func (sc *sinkConfig) writerSink(w io.Writer, format Format, level Level) (Sink, error) {
+	output := w
+	if std, ok := w.(stdStream); ok {
+		output = std.File
+	}
+	color, err := parseColor(sc.get("color", "auto"), output)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterSink(w, format, color, level), nil
+}
+
+func (sc *sinkConfig) get(key, fallback string) string {
+	if value, ok := sc.values[key]; ok {
+		return value
+	}
+	return fallback
+}
+
+func parseSize(value string) (int64, error) {
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(value, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(value, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(value, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	return size * multiplier, err
+}
+
+// stdStream keeps WriterSink.Close from closing stdout or stderr.
+type stdStream struct {
+	*os.File
+}
+
+func (stdStream) Close() error { return nil }
+
+// levelSink drops records below level before passing them on.
+type levelSink struct {
+	Sink
+	level Level
+}
+
+func (sink *levelSink) Write(r *Record) {
+	if r.Level() >= sink.level {
+		sink.Sink.Write(r)
+	}
+}
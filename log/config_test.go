@@ -0,0 +1,192 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	// Setup
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "app.log")
+	config := `
+# Errors to a rotated JSON file
+level = info
+level.test = warning
+
+[sink]
+type = file
+path = ` + fileName + `
+format = json
+level = info
+max_size = 1K
+max_files = 2
+`
+
+	// Cleanup
+	defer func() {
+		configured.Lock()
+		replaceConfiguredSinks(nil)
+		configured.Unlock()
+		ResetOutput()
+		SetLevel(LevelDebug)
+		Named("test").ResetLevel()
+	}()
+
+	// Test object
+	if err := Configure(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	DEBUG("dropped by global level")
+	Named("test").INFO("dropped by component level")
+	for i := 0; i < 20; i++ {
+		INFO("message %d %s", i, strings.Repeat("x", 100))
+	}
+
+	// Verify: the newest messages are in the current file, older ones rotated.
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var last struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil || !strings.HasPrefix(last.Message, "message 19 ") {
+		t.Errorf("Unexpected last line %q (%v)", lines[len(lines)-1], err)
+	}
+	if len(data) > 1024 {
+		t.Errorf("File was not rotated: %d bytes", len(data))
+	}
+	for _, name := range []string{fileName + ".1", fileName + ".2"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("Missing rotated file: %s", err)
+		}
+	}
+	if _, err := os.Stat(fileName + ".3"); err == nil {
+		t.Errorf("Too many rotated files kept")
+	}
+}
+
+func TestConfigure_Invalid(t *testing.T) {
+	var data = []string{
+		"level = loud",
+		"colour = true",
+		"[sink]\ntype = carrier-pigeon",
+		"[sink]\ntype = stderr\nformat = xml",
+		"just words",
+		"theme = neon",
+		"level = info#debug",
+	}
+
+	for i := range data {
+		if err := Configure(strings.NewReader(data[i])); err == nil {
+			t.Errorf("FAIL: %q was accepted", data[i])
+		}
+	}
+}
+
+func TestConfigureFromEnv(t *testing.T) {
+	// Setup
+	fileName := filepath.Join(t.TempDir(), "env.log")
+	t.Setenv("LOG_LEVEL", "warning,test=debug")
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_FILE", fileName)
+
+	// Cleanup
+	defer func() {
+		configured.Lock()
+		replaceConfiguredFile(nil)
+		configured.Unlock()
+		ResetOutput()
+		ResetFormat()
+		ResetColor()
+		SetLevel(LevelDebug)
+		Named("test").ResetLevel()
+	}()
+
+	// Test object
+	if err := ConfigureFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	first := configured.file
+	if err := ConfigureFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	INFO("dropped")
+	Named("test").DEBUG("kept")
+
+	// Verify that the file of the first call was closed.
+	if _, err := first.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("File of replaced LOG_FILE was not closed: %v", err)
+	}
+
+	// Verify
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Component string `json:"component"`
+		Message   string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil || got.Component != "test" || got.Message != "kept" {
+		t.Errorf("Unexpected output %q (%v)", data, err)
+	}
+}
+
+func TestConfigureFromEnv_Invalid(t *testing.T) {
+	// Setup
+	fileName := filepath.Join(t.TempDir(), "env.log")
+	t.Setenv("LOG_LEVEL", "warning")
+	t.Setenv("LOG_FORMAT", "xml")
+	t.Setenv("LOG_FILE", fileName)
+
+	// Test object
+	err := ConfigureFromEnv()
+
+	// Verify: nothing was applied.
+	if err == nil {
+		t.Fatal("Invalid LOG_FORMAT was accepted")
+	}
+	if GetLevel() != LevelDebug {
+		t.Errorf("Level was set to %s", GetLevel())
+	}
+	if _, err := os.Stat(fileName); err == nil {
+		t.Errorf("LOG_FILE was opened")
+	}
+}
+
+func TestConfigure_Comments(t *testing.T) {
+	// Setup
+	fileName := filepath.Join(t.TempDir(), "app#1.log")
+	config := "# comment\n" +
+		"level = info # comment\n" +
+		"[sink]\n" +
+		"type = file\t# comment\n" +
+		"path = " + fileName + "\n"
+
+	// Cleanup
+	defer func() {
+		configured.Lock()
+		replaceConfiguredSinks(nil)
+		configured.Unlock()
+		ResetOutput()
+		SetLevel(LevelDebug)
+	}()
+
+	// Test object
+	if err := Configure(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	INFO("kept")
+
+	// Verify
+	if data, err := os.ReadFile(fileName); err != nil || !strings.Contains(string(data), "kept") {
+		t.Errorf("Unexpected output %q (%v)", data, err)
+	}
+}
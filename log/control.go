@@ -0,0 +1,55 @@
+package log
+
+import (
+	"sort"
+)
+
+var controlLogger = Named("log")
+
+// Components returns the names of all named loggers, sorted.
+func Components() []string {
+	loggers.Lock()
+	defer loggers.Unlock()
+
+	names := make([]string, 0, len(loggers.m))
+	for name := range loggers.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ChangeLevel sets the level threshold of component, or the global threshold
+// if component is empty, and logs the change as an EVENT. origin tells who
+// made the change, for example "SIGUSR1" or "user 'admin'".
+func ChangeLevel(component string, level Level, origin string) {
+	l := loggerFor(component)
+	old := l.Level()
+	l.SetLevel(level)
+
+	announceLevel(component, old, level.String(), origin)
+}
+
+// InheritLevel makes component follow the threshold of its parent again, and
+// logs the change as an EVENT.
+func InheritLevel(component string, origin string) {
+	l := loggerFor(component)
+	old := l.Level()
+	l.ResetLevel()
+
+	announceLevel(component, old, "inherited "+l.Level().String(), origin)
+}
+
+func loggerFor(component string) *Logger {
+	if len(component) == 0 {
+		return rootLogger
+	}
+	return Named(component)
+}
+
+func announceLevel(component string, old Level, level string, origin string) {
+	if len(component) == 0 {
+		component = "global"
+	}
+	controlLogger.EVENT("Log level of %s set to %s (was %s) by %s", component, level, old, origin)
+}
@@ -0,0 +1,41 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChangeLevel(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	setForcedTime(time.Unix(42000000, 42000000))
+
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		resetForcedTime()
+		SetLevel(LevelDebug)
+		Named("test").ResetLevel()
+	}()
+
+	// Test object
+	ChangeLevel("", LevelError, "test")
+	WARNING("dropped")
+	ChangeLevel("test", LevelWarning, "test")
+	Named("test").WARNING("kept")
+	InheritLevel("test", "test")
+	Named("test").WARNING("dropped")
+
+	// Verify output.
+	const expected = "04:40:00.042 [log] " + eventColor + "EVENT: Log level of global set to ERROR (was DEBUG) by test" + resetColor + "\n" +
+		"04:40:00.042 [log] " + eventColor + "EVENT: Log level of test set to WARNING (was ERROR) by test" + resetColor + "\n" +
+		"04:40:00.042 [test] " + warningColor + "WARNING: kept" + resetColor + "\n" +
+		"04:40:00.042 [log] " + eventColor + "EVENT: Log level of test set to inherited ERROR (was WARNING) by test" + resetColor + "\n"
+	if out.String() != expected {
+		t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+			expected,
+			out.String())
+	}
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"sync"
+)
+
+// CounterKey identifies one log volume counter. Component is empty for
+// messages that were not logged through a named component.
+type CounterKey struct {
+	Level     Level
+	Component string
+}
+
+var counters = struct {
+	sync.Mutex
+	m map[CounterKey]uint64
+}{
+	m: map[CounterKey]uint64{},
+}
+
+func countRecord(r *Record) {
+	key := CounterKey{
+		Level:     r.Level(),
+		Component: r.Component(),
+	}
+
+	counters.Lock()
+	defer counters.Unlock()
+
+	counters.m[key]++
+}
+
+// Counts returns a snapshot of the number of records emitted so far. Every
+// level is present in the result, even if nothing was logged at it.
+func Counts() map[CounterKey]uint64 {
+	counters.Lock()
+	defer counters.Unlock()
+
+	snapshot := make(map[CounterKey]uint64, len(counters.m)+len(levelNames))
+	for level := range levelNames {
+		snapshot[CounterKey{Level: Level(level)}] = 0
+	}
+	for key, count := range counters.m {
+		snapshot[key] = count
+	}
+	return snapshot
+}
+
+func resetCounts() {
+	counters.Lock()
+	defer counters.Unlock()
+
+	counters.m = map[CounterKey]uint64{}
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"io"
+	"testing"
+)
+
+func TestCounts(t *testing.T) {
+	// Setup
+	SetOutput(io.Discard)
+	resetCounts()
+
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		resetCounts()
+	}()
+
+	// Test object
+	DEBUG("one")
+	WARNING("two")
+	WARNING("three")
+	chain := Chain(InfoMsg("four"))
+	chain.Add(ErrorMsg("five"))
+	chain.Write()
+
+	// Verify counters. A chain counts once, at its most severe level.
+	expected := map[Level]uint64{
+		LevelDebug:   1,
+		LevelInfo:    0,
+		LevelEvent:   0,
+		LevelWarning: 2,
+		LevelError:   1,
+		LevelFatal:   0,
+	}
+	counts := Counts()
+	for level, want := range expected {
+		if got := counts[CounterKey{Level: level}]; got != want {
+			t.Errorf("%s: got %d, want %d", level, got, want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cause describes one error in a wrapped error tree.
+type Cause struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Depth   int    `json:"depth"`
+
+	// The part of Message that is not repeated from the wrapped errors.
+	own string
+}
+
+// maxCauseDepth guards against Unwrap implementations that form a cycle.
+const maxCauseDepth = 32
+
+// Causes walks the error tree of err, following both Unwrap() error and
+// Unwrap() []error (errors.Join), and returns the errors in depth-first order.
+func Causes(err error) []Cause {
+	var causes []Cause
+	walkCauses(err, 0, &causes)
+	return causes
+}
+
+func walkCauses(err error, depth int, causes *[]Cause) {
+	if err == nil || depth > maxCauseDepth {
+		return
+	}
+
+	var children []error
+	switch errT := err.(type) {
+	case interface{ Unwrap() []error }:
+		children = errT.Unwrap()
+	case interface{ Unwrap() error }:
+		if child := errT.Unwrap(); child != nil {
+			children = []error{child}
+		}
+	}
+
+	message := err.Error()
+	*causes = append(*causes, Cause{
+		Type:    fmt.Sprintf("%T", err),
+		Message: limitLength(message),
+		Depth:   depth,
+		own:     ownMessage(message, children),
+	})
+
+	for _, child := range children {
+		walkCauses(child, depth+1, causes)
+	}
+}
+
+// ownMessage strips the text of the wrapped errors from message, so that
+// "open config: no such file" wrapping "no such file" becomes "open config".
+func ownMessage(message string, children []error) string {
+	cut := len(message)
+	for _, child := range children {
+		if child == nil || len(child.Error()) == 0 {
+			continue
+		}
+		if idx := strings.Index(message, child.Error()); idx >= 0 && idx < cut {
+			cut = idx
+		}
+	}
+	return strings.TrimRight(message[:cut], ": \n")
+}
+
+// ------------------------------------------------------------
+
+// ErrMsg returns an ERROR message for err. Every error in the wrapped error
+// tree is rendered as its own chained segment, and the full list of causes is
+// kept for structured output.
+func ErrMsg(err error) Message {
+	if err == nil {
+		return ErrorMsg("nil")
+	}
+
+	causes := Causes(err)
+
+	var parts []string
+	for _, c := range causes {
+		if len(c.own) > 0 {
+			parts = append(parts, limitLength(c.own))
+		}
+	}
+	if parts == nil {
+		parts = []string{limitLength(err.Error())}
+	}
+
+	return Message{
+		panic:     false,
+		level:     LevelError,
+		formatted: true,
+		text:      strings.Join(parts, chainSeparator),
+		parts:     parts,
+		causes:    causes,
+	}
+}
+
+func ERR(err error) {
+	if !rootLogger.Enabled(LevelError) {
+		return
+	}
+	ErrMsg(err).write()
+}
@@ -0,0 +1,88 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErr_Wrapped(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	setForcedTime(time.Unix(42000000, 42000000))
+
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		resetForcedTime()
+	}()
+
+	// Test object
+	root := &fs.PathError{Op: "open", Path: "sessions.json", Err: fs.ErrNotExist}
+	ERR(fmt.Errorf("load sessions: %w", root))
+
+	// Verify output.
+	const expected = "04:40:00.042 " + errorColor + "ERROR: load sessions" + resetColor + " -> " +
+		errorColor + "open sessions.json" + resetColor + " -> " +
+		errorColor + "file does not exist" + resetColor + "\n"
+	if out.String() != expected {
+		t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+			expected,
+			out.String())
+	}
+}
+
+func TestErr_JoinedJSON(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	SetFormat(FormatJSON)
+
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		ResetFormat()
+	}()
+
+	// Test object
+	ERR(fmt.Errorf("save: %w", errors.Join(errors.New("disk full"), errors.New("quota"))))
+
+	// Verify output.
+	var got struct {
+		Level   string  `json:"level"`
+		Message string  `json:"message"`
+		Errors  []Cause `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &got); err != nil {
+		t.Fatalf("Invalid JSON output %q: %s", out.String(), err)
+	}
+
+	if got.Level != "ERROR" || got.Message != "save -> disk full -> quota" {
+		t.Errorf("Unexpected level/message: %s %q", got.Level, got.Message)
+	}
+
+	expected := []struct {
+		typ   string
+		depth int
+	}{
+		{"*fmt.wrapError", 0},
+		{"*errors.joinError", 1},
+		{"*errors.errorString", 2},
+		{"*errors.errorString", 2},
+	}
+	if len(got.Errors) != len(expected) {
+		t.Fatalf("Expected %d causes, got %d: %+v", len(expected), len(got.Errors), got.Errors)
+	}
+	for idx := range expected {
+		if got.Errors[idx].Type != expected[idx].typ || got.Errors[idx].Depth != expected[idx].depth {
+			t.Errorf("Cause %d: got %s/%d, want %s/%d", idx,
+				got.Errors[idx].Type, got.Errors[idx].Depth,
+				expected[idx].typ, expected[idx].depth)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format selects how records are rendered to the output.
+type Format int
+
+const (
+	// FormatText renders one colored, human readable line per record.
+	FormatText Format = iota
+	// FormatJSON renders one JSON object per line.
+	FormatJSON
+	// FormatGELF renders one GELF 1.1 message per line.
+	FormatGELF
+	// FormatOTel renders one OpenTelemetry LogRecord (OTLP/JSON encoding) per
+	// line.
+	FormatOTel
+)
+
+func SetFormat(f Format) { changeOutput(func(s *outputSettings) { s.format = f }) }
+func ResetFormat()       { SetFormat(FormatText) }
+
+// SetColor turns the ANSI colors of FormatText output on or off.
+func SetColor(on bool) { changeOutput(func(s *outputSettings) { s.color = on }) }
+func ResetColor()      { SetColor(true) }
+
+// ParseFormat returns the format with the given name: "text", "json", "gelf"
+// or "otel".
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "text":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	case "gelf":
+		return FormatGELF, nil
+	case "otel":
+		return FormatOTel, nil
+	default:
+		return FormatText, fmt.Errorf("log.ParseFormat: unknown format '%s'", name)
+	}
+}
+
+// appendRecord appends r to dst. color applies to FormatText only.
+func (f Format) appendRecord(dst []byte, r *Record, color bool) []byte {
+	switch f {
+	case FormatJSON:
+		return appendJSONRecord(dst, r)
+	case FormatGELF:
+		return appendGELFRecord(dst, r)
+	case FormatOTel:
+		return appendOTelRecord(dst, r)
+	default:
+		return appendTextRecord(dst, r, color)
+	}
+}
+
+// bufferPool holds the buffers records are formatted into before writing.
+var bufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 256)
+		return &buf
+	},
+}
+
+// maxPooledBuffer keeps the occasional huge record from pinning memory.
+const maxPooledBuffer = 64 << 10
+
+func writeOutput(r *Record) {
+	if s := mainOutput.settings.Load(); s.w != nil {
+		writeFormatted(s.w, s.format, s.color, r)
+	}
+}
+
+func writeFormatted(w io.Writer, f Format, color bool, r *Record) {
+	bufPtr := bufferPool.Get().(*[]byte)
+
+	buf := f.appendRecord((*bufPtr)[:0], r, color)
+	w.Write(buf)
+
+	if cap(buf) <= maxPooledBuffer {
+		*bufPtr = buf
+		bufferPool.Put(bufPtr)
+	}
+}
+
+// ------------------------------------------------------------
+
+func appendTextRecord(dst []byte, r *Record, color bool) []byte {
+	dst = r.Time.AppendFormat(dst, "15:04:05.000")
+	dst = append(dst, ' ')
+	if name := r.Component(); len(name) > 0 {
+		dst = append(dst, '[')
+		dst = append(dst, name...)
+		dst = append(dst, "] "...)
+	}
+
+	dst = appendLines(dst, r.Messages, true, color)
+
+	for _, f := range r.Fields() {
+		dst = append(dst, ' ')
+		dst = append(dst, f.Key...)
+		dst = append(dst, '=')
+		dst = append(dst, fieldText(f.Value)...)
+	}
+	if r.Trace.IsValid() {
+		dst = append(dst, " trace_id="...)
+		dst = append(dst, r.Trace.TraceIDString()...)
+		dst = append(dst, " span_id="...)
+		dst = append(dst, r.Trace.SpanIDString()...)
+	}
+	return append(dst, '\n')
+}
+
+func fieldText(value any) string {
+	text := fmt.Sprint(value)
+	if len(text) == 0 || strings.ContainsAny(text, " \t\n\"=") {
+		return strconv.Quote(text)
+	}
+	return text
+}
+
+// ------------------------------------------------------------
+
+type jsonSegment struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+type jsonRecord struct {
+	Time      string         `json:"time"`
+	Level     string         `json:"level"`
+	Component string         `json:"component,omitempty"`
+	Message   string         `json:"message"`
+	Chain     []jsonSegment  `json:"chain,omitempty"`
+	Errors    []Cause        `json:"errors,omitempty"`
+	Fields    map[string]any `json:"fields,omitempty"`
+	TraceID   string         `json:"trace_id,omitempty"`
+	SpanID    string         `json:"span_id,omitempty"`
+}
+
+func appendJSONRecord(dst []byte, r *Record) []byte {
+	out := jsonRecord{
+		Time:      r.Time.Format(time.RFC3339Nano),
+		Level:     r.Level().String(),
+		Component: r.Component(),
+	}
+
+	texts := make([]string, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		texts = append(texts, m.Text())
+		out.Errors = append(out.Errors, m.causes...)
+	}
+	out.Message = strings.Join(texts, chainSeparator)
+
+	if len(r.Messages) > 1 {
+		for _, m := range r.Messages {
+			out.Chain = append(out.Chain, jsonSegment{
+				Level:   m.level.String(),
+				Message: m.Text(),
+			})
+		}
+	}
+
+	if fields := r.Fields(); len(fields) > 0 {
+		out.Fields = make(map[string]any, len(fields))
+		for _, f := range fields {
+			out.Fields[f.Key] = jsonValue(f.Value)
+		}
+	}
+
+	if r.Trace.IsValid() {
+		out.TraceID = r.Trace.TraceIDString()
+		out.SpanID = r.Trace.SpanIDString()
+	}
+
+	// Field values have been checked by jsonValue, so Marshal cannot fail.
+	data, _ := json.Marshal(&out)
+	dst = append(dst, data...)
+	return append(dst, '\n')
+}
+
+// jsonValue returns value as is if it can be encoded, or else its fmt text.
+func jsonValue(value any) any {
+	if err, ok := value.(error); ok {
+		return err.Error()
+	}
+	if _, err := json.Marshal(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	return value
+}
@@ -0,0 +1,189 @@
+package log
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// gelfLevels maps levels to the syslog severities used by GELF.
+var gelfLevels = [...]int{
+	LevelDebug:   7, // debug
+	LevelInfo:    6, // informational
+	LevelEvent:   5, // notice
+	LevelWarning: 4, // warning
+	LevelError:   3, // error
+	LevelFatal:   2, // critical
+}
+
+var gelfHost = hostname()
+
+func hostname() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+	return host
+}
+
+// appendGELFRecord renders r as a GELF 1.1 message. The component, chain
+// segments, error causes and fields become additional ("_") fields.
+func appendGELFRecord(dst []byte, r *Record) []byte {
+	texts := make([]string, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		texts = append(texts, m.Text())
+	}
+	message := strings.Join(texts, chainSeparator)
+
+	out := map[string]any{
+		"version":       "1.1",
+		"host":          gelfHost,
+		"short_message": message,
+		"timestamp":     json.Number(strconv.FormatFloat(float64(r.Time.UnixMicro())/1e6, 'f', 6, 64)),
+		"level":         gelfLevels[r.Level()],
+	}
+
+	// GELF viewers show only the first line of short_message.
+	if first, _, multiLine := strings.Cut(message, "\n"); multiLine {
+		out["short_message"] = first
+		out["full_message"] = message
+	}
+
+	if name := r.Component(); len(name) > 0 {
+		out["_component"] = name
+	}
+	if len(r.Messages) > 1 {
+		for idx, m := range r.Messages {
+			out[fmt.Sprintf("_chain_%d", idx)] = m.level.String() + ": " + texts[idx]
+		}
+	}
+	var causeIdx int
+	for _, m := range r.Messages {
+		for _, c := range m.causes {
+			out[fmt.Sprintf("_error_%d_type", causeIdx)] = c.Type
+			out[fmt.Sprintf("_error_%d_message", causeIdx)] = c.Message
+			causeIdx++
+		}
+	}
+	for _, f := range r.Fields() {
+		out["_"+gelfFieldName(f.Key)] = gelfValue(f.Value)
+	}
+
+	if r.Trace.IsValid() {
+		out["_trace_id"] = r.Trace.TraceIDString()
+		out["_span_id"] = r.Trace.SpanIDString()
+	}
+
+	// Only strings and numbers, so Marshal cannot fail.
+	data, _ := json.Marshal(out)
+	dst = append(dst, data...)
+	return append(dst, '\n')
+}
+
+// gelfFieldName makes key a valid GELF additional field name.
+func gelfFieldName(key string) string {
+	if key == "id" {
+		// "_id" is reserved.
+		return "field_id"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+// gelfValue keeps numbers as they are; GELF allows only strings otherwise.
+func gelfValue(value any) any {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		// NaN and infinities can't be encoded as JSON numbers.
+		if _, err := json.Marshal(value); err == nil {
+			return value
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// ------------------------------------------------------------
+
+// GELFUDPSink sends records as GELF messages over UDP. Messages that don't
+// fit in one datagram are split into GELF chunks; messages that would need
+// more than 128 chunks are dropped, as GELF doesn't allow that many, and so
+// are messages that can't be sent. Dropped counts both.
+type GELFUDPSink struct {
+	conn    net.Conn
+	dropped atomic.Uint64
+}
+
+const (
+	gelfChunkSize  = 1420
+	gelfMaxChunks  = 128
+	gelfHeaderSize = 12
+)
+
+func NewGELFUDPSink(address string) (*GELFUDPSink, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &GELFUDPSink{conn: conn}, nil
+}
+
+func (sink *GELFUDPSink) Write(r *Record) {
+	data := appendGELFRecord(nil, r)
+	data = data[:len(data)-1] // no trailing newline
+	if err := sink.send(data); err != nil {
+		sink.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of records dropped because they were too large
+// or could not be sent.
+func (sink *GELFUDPSink) Dropped() uint64 {
+	return sink.dropped.Load()
+}
+
+func (sink *GELFUDPSink) Close() error {
+	return sink.conn.Close()
+}
+
+func (sink *GELFUDPSink) send(data []byte) error {
+	if len(data) <= gelfChunkSize {
+		_, err := sink.conn.Write(data)
+		return err
+	}
+
+	payloadSize := gelfChunkSize - gelfHeaderSize
+	count := (len(data) + payloadSize - 1) / payloadSize
+	if count > gelfMaxChunks {
+		return errors.New("log.GELFUDPSink: message too large")
+	}
+
+	// Chunk header: magic bytes, message id, sequence number, sequence count
+	var chunk [gelfChunkSize]byte
+	chunk[0], chunk[1] = 0x1e, 0x0f
+	if _, err := rand.Read(chunk[2:10]); err != nil {
+		return err
+	}
+	chunk[11] = byte(count)
+
+	for seq := 0; seq < count; seq++ {
+		chunk[10] = byte(seq)
+		n := copy(chunk[gelfHeaderSize:], data[seq*payloadSize:])
+		if _, err := sink.conn.Write(chunk[:gelfHeaderSize+n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGELF_Format(t *testing.T) {
+	// Test object
+	r := &Record{
+		Time:     time.Unix(42000000, 42000000),
+		Messages: []Message{Named("test").With("id", 7).EventMsg("sign-in"), WarningMsg("slow\nreally")},
+	}
+	data := appendGELFRecord(nil, r)
+
+	// Verify
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Invalid JSON %q: %s", data, err)
+	}
+
+	expected := map[string]any{
+		"version":       "1.1",
+		"short_message": "sign-in -> slow",
+		"full_message":  "sign-in -> slow\nreally",
+		"timestamp":     42000000.042,
+		"level":         4.0,
+		"_component":    "test",
+		"_chain_0":      "EVENT: sign-in",
+		"_chain_1":      "WARNING: slow\nreally",
+		"_field_id":     7.0,
+	}
+	for key, want := range expected {
+		if got[key] != want {
+			t.Errorf("%s: got %v, want %v", key, got[key], want)
+		}
+	}
+}
+
+func TestGELF_Chunked(t *testing.T) {
+	// Setup
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	sink, err := NewGELFUDPSink(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	// Test object
+	long := strings.Repeat("x", 3*gelfChunkSize)
+	sink.Write(&Record{Time: time.Now(), Messages: []Message{InfoMsg("%s", long)}})
+
+	// Verify: reassemble the chunks.
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var chunks [][]byte
+	var count int
+	for count == 0 || len(chunks) < count {
+		buf := make([]byte, 2*gelfChunkSize)
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunk := buf[:n]
+		if n > gelfChunkSize || chunk[0] != 0x1e || chunk[1] != 0x0f {
+			t.Fatalf("Invalid chunk header % x (%d bytes)", chunk[:gelfHeaderSize], n)
+		}
+		if count == 0 {
+			count = int(chunk[11])
+			chunks = make([][]byte, 0, count)
+		}
+		if int(chunk[10]) != len(chunks) {
+			t.Fatalf("Chunk %d arrived as %d", chunk[10], len(chunks))
+		}
+		chunks = append(chunks, chunk[gelfHeaderSize:])
+	}
+
+	var got struct {
+		Message string `json:"short_message"`
+	}
+	if err := json.Unmarshal(bytes.Join(chunks, nil), &got); err != nil || got.Message != long {
+		t.Errorf("Reassembled message is broken (%d chunks): %v", count, err)
+	}
+}
+
+func TestGELF_TooLarge(t *testing.T) {
+	// Setup
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	sink, err := NewGELFUDPSink(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	// Test object
+	long := strings.Repeat("x", (gelfMaxChunks+1)*gelfChunkSize)
+	sink.Write(&Record{Time: time.Now(), Messages: []Message{InfoMsg("%s", long)}})
+
+	// Verify output.
+	if sink.Dropped() != 1 {
+		t.Errorf("FAIL: %d records dropped, want 1", sink.Dropped())
+	}
+}
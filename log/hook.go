@@ -0,0 +1,194 @@
+package log
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Hook is called with every record whose level is listed in Levels (or every
+// record, if Levels is empty).
+//
+// Synchronous hooks run in the logging goroutine before the log call returns,
+// which makes them the right choice for FATAL: the panic happens only after
+// they have finished. Asynchronous hooks run in a goroutine of their own and
+// drop records if they fall more than hookQueueSize records behind.
+//
+// A panicking hook is recovered and reported as ERROR. Records logged from
+// inside a hook are written to the output but not passed to hooks again,
+// unless the hook logs them from another goroutine.
+type Hook struct {
+	Levels []Level
+	Async  bool
+	Func   func(*Record)
+}
+
+const hookQueueSize = 256
+
+type hookEntry struct {
+	hook Hook
+
+	// Asynchronous hooks only
+	queue  chan *Record
+	mutex  sync.RWMutex
+	closed bool
+}
+
+var hooks struct {
+	sync.RWMutex
+	list []*hookEntry
+}
+
+// hookActive counts the hooks running right now, so that inHook is only
+// consulted while one is.
+var hookActive atomic.Int32
+
+// invokeFunc is the name of hookEntry.invoke, as it appears in stack traces.
+var invokeFunc string
+
+func init() {
+	invokeFunc = runtime.FuncForPC(reflect.ValueOf((*hookEntry).invoke).Pointer()).Name()
+}
+
+// maxHookDepth is the number of stack frames inHook looks through.
+const maxHookDepth = 64
+
+// AddHook registers a hook and returns a function that removes it again.
+func AddHook(h Hook) (remove func()) {
+	entry := &hookEntry{hook: h}
+	if h.Async {
+		entry.queue = make(chan *Record, hookQueueSize)
+		go entry.worker()
+	}
+
+	hooks.Lock()
+	defer hooks.Unlock()
+
+	// Copy-on-write, so that runHooks can iterate without holding the lock.
+	list := make([]*hookEntry, len(hooks.list), len(hooks.list)+1)
+	copy(list, hooks.list)
+	hooks.list = append(list, entry)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { removeHook(entry) })
+	}
+}
+
+func removeHook(entry *hookEntry) {
+	hooks.Lock()
+	defer hooks.Unlock()
+
+	list := make([]*hookEntry, 0, len(hooks.list))
+	for _, e := range hooks.list {
+		if e != entry {
+			list = append(list, e)
+		}
+	}
+	hooks.list = list
+
+	if entry.queue != nil {
+		entry.mutex.Lock()
+		defer entry.mutex.Unlock()
+
+		entry.closed = true
+		close(entry.queue)
+	}
+}
+
+// ------------------------------------------------------------
+
+func runHooks(r *Record) {
+	hooks.RLock()
+	list := hooks.list
+	hooks.RUnlock()
+
+	level := r.Level()
+	accepted := false
+	for _, entry := range list {
+		if entry.accepts(level) {
+			accepted = true
+			break
+		}
+	}
+	if !accepted {
+		return
+	}
+
+	// Walk the stack only for records that some hook would see.
+	if hookActive.Load() > 0 && inHook() {
+		return
+	}
+
+	for _, entry := range list {
+		if !entry.accepts(level) {
+			continue
+		}
+
+		if entry.queue != nil {
+			entry.enqueue(r)
+		} else {
+			entry.invoke(r)
+		}
+	}
+}
+
+func (entry *hookEntry) accepts(level Level) bool {
+	if len(entry.hook.Levels) == 0 {
+		return true
+	}
+	for _, l := range entry.hook.Levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+func (entry *hookEntry) enqueue(r *Record) {
+	entry.mutex.RLock()
+	defer entry.mutex.RUnlock()
+
+	if entry.closed {
+		return
+	}
+
+	select {
+	case entry.queue <- r:
+	default:
+	}
+}
+
+// inHook tells if the calling goroutine is running a hook.
+func inHook() bool {
+	var pcs [maxHookDepth]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if frame.Function == invokeFunc {
+			return true
+		}
+		if !more {
+			return false
+		}
+	}
+}
+
+func (entry *hookEntry) invoke(r *Record) {
+	hookActive.Add(1)
+	defer hookActive.Add(-1)
+	defer func() {
+		if err := recover(); err != nil {
+			ERROR("log: hook panicked: %v", err)
+		}
+	}()
+
+	entry.hook.Func(r)
+}
+
+func (entry *hookEntry) worker() {
+	for r := range entry.queue {
+		entry.invoke(r)
+	}
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHook_Sync(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+
+	var received []string
+	remove := AddHook(Hook{
+		Levels: []Level{LevelError, LevelFatal},
+		Func: func(r *Record) {
+			received = append(received, r.Messages[0].Text())
+			// Logging from a hook must not recurse into the hook.
+			WARNING("hook saw %s", r.Messages[0].Text())
+		},
+	})
+
+	// Cleanup
+	defer func() {
+		remove()
+		ResetOutput()
+	}()
+
+	// Test object
+	INFO("ignored")
+	ERROR("first")
+	ERROR("second")
+
+	// Verify
+	if strings.Join(received, ",") != "first,second" {
+		t.Errorf("Hook received %q", received)
+	}
+	if strings.Count(out.String(), "hook saw") != 2 {
+		t.Errorf("Output missing hook messages:\n%s", out.String())
+	}
+}
+
+func TestHook_Panic(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+
+	remove := AddHook(Hook{
+		Func: func(r *Record) { panic("boom") },
+	})
+
+	// Cleanup
+	defer func() {
+		remove()
+		ResetOutput()
+	}()
+
+	// Test object
+	INFO("message")
+
+	// Verify
+	if !strings.Contains(out.String(), "ERROR: log: hook panicked: boom") {
+		t.Errorf("Output does not report hook panic:\n%s", out.String())
+	}
+}
+
+func TestHook_Async(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+
+	received := make(chan string, 1)
+	remove := AddHook(Hook{
+		Levels: []Level{LevelEvent},
+		Async:  true,
+		Func: func(r *Record) {
+			received <- r.Messages[0].Text()
+		},
+	})
+
+	// Cleanup
+	defer func() {
+		remove()
+		ResetOutput()
+	}()
+
+	// Test object
+	EVENT("sign-in")
+
+	// Verify
+	select {
+	case text := <-received:
+		if text != "sign-in" {
+			t.Errorf("Hook received %q", text)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Asynchronous hook was not called")
+	}
+}
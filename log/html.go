@@ -0,0 +1,243 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ansiPalette holds the 16 basic colors, as rendered by xterm.
+var ansiPalette = [16][3]uint8{
+	{0x00, 0x00, 0x00}, {0xcd, 0x00, 0x00}, {0x00, 0xcd, 0x00}, {0xcd, 0xcd, 0x00},
+	{0x00, 0x00, 0xee}, {0xcd, 0x00, 0xcd}, {0x00, 0xcd, 0xcd}, {0xe5, 0xe5, 0xe5},
+	{0x7f, 0x7f, 0x7f}, {0xff, 0x00, 0x00}, {0x00, 0xff, 0x00}, {0xff, 0xff, 0x00},
+	{0x5c, 0x5c, 0xff}, {0xff, 0x00, 0xff}, {0x00, 0xff, 0xff}, {0xff, 0xff, 0xff},
+}
+
+// HTMLStyle is a style sheet for the CSS classes used by AppendHTML.
+var HTMLStyle = htmlStyle()
+
+func htmlStyle() string {
+	var out strings.Builder
+	out.WriteString(".ansi-bold { font-weight: bold; }\n")
+	for idx, rgb := range ansiPalette {
+		fmt.Fprintf(&out, ".ansi-fg-%d { color: #%02x%02x%02x; }\n", idx, rgb[0], rgb[1], rgb[2])
+	}
+	for idx, rgb := range ansiPalette {
+		fmt.Fprintf(&out, ".ansi-bg-%d { background-color: #%02x%02x%02x; }\n", idx, rgb[0], rgb[1], rgb[2])
+	}
+	return out.String()
+}
+
+// ansiColor is a color set by an SGR sequence: one of the 16 basic colors
+// (index 0-15), or an RGB color (index -1).
+type ansiColor struct {
+	set   bool
+	index int
+	rgb   [3]uint8
+}
+
+func (c ansiColor) css() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.rgb[0], c.rgb[1], c.rgb[2])
+}
+
+type ansiState struct {
+	bold   bool
+	fg, bg ansiColor
+}
+
+// AppendHTML appends text, which may contain ANSI escape sequences like
+// colored FormatText output, to dst as HTML. Colored runs become <span>
+// elements: the basic colors and bold use the classes of HTMLStyle, 256-color
+// and truecolor runs inline styles. Other escape sequences are dropped.
+func AppendHTML(dst []byte, text []byte) []byte {
+	var state ansiState
+	open, changed := false, false
+
+	for len(text) > 0 {
+		esc := bytes.IndexByte(text, '\x1B')
+		if esc < 0 {
+			esc = len(text)
+		}
+		if esc > 0 {
+			// Spans are opened only for non-empty runs of text.
+			if changed {
+				if open {
+					dst = append(dst, "</span>"...)
+				}
+				dst, open = state.appendSpan(dst)
+				changed = false
+			}
+			dst = appendHTMLEscaped(dst, text[:esc])
+			text = text[esc:]
+		}
+		if len(text) == 0 {
+			break
+		}
+
+		params, final, rest := splitEscape(text)
+		text = rest
+		if final == 'm' {
+			state.apply(params)
+			changed = true
+		}
+	}
+
+	if open {
+		dst = append(dst, "</span>"...)
+	}
+	return dst
+}
+
+// splitEscape splits a CSI sequence ("\x1B[" params final) off the start of
+// text. For other escapes only the ESC byte is consumed, and final is zero.
+func splitEscape(text []byte) (params string, final byte, rest []byte) {
+	if len(text) < 2 || text[1] != '[' {
+		return "", 0, text[1:]
+	}
+	for idx := 2; idx < len(text); idx++ {
+		if c := text[idx]; c >= 0x40 && c <= 0x7e {
+			return string(text[2:idx]), c, text[idx+1:]
+		}
+	}
+	return "", 0, nil
+}
+
+func (s *ansiState) apply(params string) {
+	codes := strings.Split(params, ";")
+	for idx := 0; idx < len(codes); idx++ {
+		code, _ := strconv.Atoi(codes[idx]) // an empty code means 0
+		switch {
+		case code == 0:
+			*s = ansiState{}
+		case code == 1:
+			s.bold = true
+		case code == 22:
+			s.bold = false
+		case code >= 30 && code <= 37:
+			s.fg = ansiColor{set: true, index: code - 30}
+		case code >= 90 && code <= 97:
+			s.fg = ansiColor{set: true, index: code - 90 + 8}
+		case code == 39:
+			s.fg = ansiColor{}
+		case code >= 40 && code <= 47:
+			s.bg = ansiColor{set: true, index: code - 40}
+		case code >= 100 && code <= 107:
+			s.bg = ansiColor{set: true, index: code - 100 + 8}
+		case code == 49:
+			s.bg = ansiColor{}
+		case code == 38 || code == 48:
+			color, used := extendedColor(codes[idx+1:])
+			idx += used
+			if code == 38 {
+				s.fg = color
+			} else {
+				s.bg = color
+			}
+		}
+	}
+}
+
+// extendedColor parses the arguments of an extended color code, "5;n" or
+// "2;r;g;b", and returns the color and the number of arguments used.
+func extendedColor(args []string) (ansiColor, int) {
+	number := func(idx int) uint8 {
+		if idx >= len(args) {
+			return 0
+		}
+		n, _ := strconv.Atoi(args[idx])
+		return uint8(n)
+	}
+
+	if len(args) == 0 {
+		return ansiColor{}, 0
+	}
+	switch args[0] {
+	case "5":
+		return color256(number(1)), 2
+	case "2":
+		return ansiColor{set: true, index: -1, rgb: [3]uint8{number(1), number(2), number(3)}}, 4
+	default:
+		return ansiColor{}, 1
+	}
+}
+
+// color256 converts a color of the xterm 256-color palette.
+func color256(n uint8) ansiColor {
+	switch {
+	case n < 16:
+		return ansiColor{set: true, index: int(n)}
+	case n < 232:
+		level := func(v uint8) uint8 {
+			if v == 0 {
+				return 0
+			}
+			return 55 + 40*v
+		}
+		n -= 16
+		return ansiColor{set: true, index: -1, rgb: [3]uint8{level(n / 36), level(n / 6 % 6), level(n % 6)}}
+	default:
+		gray := 8 + 10*(n-232)
+		return ansiColor{set: true, index: -1, rgb: [3]uint8{gray, gray, gray}}
+	}
+}
+
+// appendSpan opens a span for the state, unless it is the default state.
+func (s *ansiState) appendSpan(dst []byte) ([]byte, bool) {
+	var classes, styles []string
+	if s.bold {
+		classes = append(classes, "ansi-bold")
+	}
+	switch {
+	case !s.fg.set:
+	case s.fg.index >= 0:
+		classes = append(classes, "ansi-fg-"+strconv.Itoa(s.fg.index))
+	default:
+		styles = append(styles, "color: "+s.fg.css())
+	}
+	switch {
+	case !s.bg.set:
+	case s.bg.index >= 0:
+		classes = append(classes, "ansi-bg-"+strconv.Itoa(s.bg.index))
+	default:
+		styles = append(styles, "background-color: "+s.bg.css())
+	}
+
+	if len(classes) == 0 && len(styles) == 0 {
+		return dst, false
+	}
+
+	dst = append(dst, "<span"...)
+	if len(classes) > 0 {
+		dst = append(dst, ` class="`...)
+		dst = append(dst, strings.Join(classes, " ")...)
+		dst = append(dst, '"')
+	}
+	if len(styles) > 0 {
+		dst = append(dst, ` style="`...)
+		dst = append(dst, strings.Join(styles, "; ")...)
+		dst = append(dst, '"')
+	}
+	return append(dst, '>'), true
+}
+
+func appendHTMLEscaped(dst []byte, text []byte) []byte {
+	for _, c := range text {
+		switch c {
+		case '&':
+			dst = append(dst, "&amp;"...)
+		case '<':
+			dst = append(dst, "&lt;"...)
+		case '>':
+			dst = append(dst, "&gt;"...)
+		case '"':
+			dst = append(dst, "&#34;"...)
+		case '\'':
+			dst = append(dst, "&#39;"...)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the severity of a log message. Levels are ordered from the least
+// severe (LevelDebug) to the most severe (LevelFatal).
+//
+// LevelEvent marks messages that record what happened, such as sign-ins and
+// configuration changes, rather than a severity. The level thresholds of
+// loggers do not apply to it: EVENT messages are written whatever the
+// threshold, and so are records that chain one. Such records can't be
+// quieted with SetLevel, and an AuditSink syncs each of them to disk, so
+// EVENT is not meant for records logged on every request.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelEvent
+	LevelWarning
+	LevelError
+	LevelFatal
+)
+
+var levelNames = [...]string{
+	LevelDebug:   "DEBUG",
+	LevelInfo:    "INFO",
+	LevelEvent:   "EVENT",
+	LevelWarning: "WARNING",
+	LevelError:   "ERROR",
+	LevelFatal:   "FATAL",
+}
+
+func (l Level) String() string {
+	if l >= 0 && int(l) < len(levelNames) {
+		return levelNames[l]
+	}
+	return "UNKNOWN"
+}
+
+// ParseLevel returns the level with the given name, ignoring case. "WARN" is
+// accepted for LevelWarning.
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "WARN" {
+		return LevelWarning, nil
+	}
+	for level, levelName := range levelNames {
+		if name == levelName {
+			return Level(level), nil
+		}
+	}
+	return LevelDebug, fmt.Errorf("log.ParseLevel: unknown level '%s'", name)
+}
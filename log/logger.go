@@ -0,0 +1,244 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Field is a key-value pair attached to every message of a Logger.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Logger is a named component logger. Loggers form a tree by their dotted
+// names ("http", "http.session"); a logger without a level threshold of its
+// own uses the one of its parent, and the root of the tree is controlled by
+// the package-level SetLevel.
+type Logger struct {
+	name   string
+	parent *Logger
+	level  atomic.Int32
+	fields []Field
+	trace  TraceContext
+}
+
+const levelUnset = -1
+
+var rootLogger = newLogger("", nil, nil)
+
+var loggers = struct {
+	sync.Mutex
+	m map[string]*Logger
+}{
+	m: map[string]*Logger{},
+}
+
+func newLogger(name string, parent *Logger, fields []Field) *Logger {
+	l := &Logger{
+		name:   name,
+		parent: parent,
+		fields: fields,
+	}
+	if parent == nil {
+		l.level.Store(int32(LevelDebug))
+	} else {
+		l.level.Store(levelUnset)
+	}
+	return l
+}
+
+// Named returns the logger for component name, creating it on first use.
+func Named(name string) *Logger {
+	return rootLogger.Named(name)
+}
+
+// SetLevel sets the minimum level of messages that are written. Named
+// loggers without a threshold of their own inherit it.
+func SetLevel(level Level) { rootLogger.SetLevel(level) }
+func GetLevel() Level      { return rootLogger.Level() }
+
+// ------------------------------------------------------------
+
+// Named returns the sub-logger "<l.Name()>.<name>", creating it on first use.
+// Sub-loggers are shared by component name. If l carries fields or a trace,
+// the result is a logger of its own that adds them and follows the level
+// threshold of the shared sub-logger.
+func (l *Logger) Named(name string) *Logger {
+	if len(l.name) > 0 {
+		name = l.name + "." + name
+	}
+
+	loggers.Lock()
+	child, exists := loggers.m[name]
+	if !exists {
+		child = newLogger(name, registered(l), nil)
+		loggers.m[name] = child
+	}
+	loggers.Unlock()
+
+	if len(l.fields) == 0 && l.trace == (TraceContext{}) {
+		return child
+	}
+	own := newLogger(name, child, l.fields)
+	own.trace = l.trace
+	return own
+}
+
+// registered returns the shared logger of the component of l. The caller
+// holds loggers.
+func registered(l *Logger) *Logger {
+	if shared, ok := loggers.m[l.name]; ok {
+		return shared
+	}
+	return rootLogger
+}
+
+// With returns a logger for the same component that adds a field to every
+// message. The returned logger follows the level threshold of l.
+func (l *Logger) With(key string, value any) *Logger {
+	return l.WithFields(Field{Key: key, Value: value})
+}
+
+// WithFields is like With, for several fields at once.
+func (l *Logger) WithFields(fields ...Field) *Logger {
+	fields = append(append(make([]Field, 0, len(l.fields)+len(fields)), l.fields...), fields...)
+
+	child := newLogger(l.name, l, fields)
+	child.trace = l.trace
+	return child
+}
+
+// WithTrace returns a logger for the same component that attaches tc to its
+// records. Like With, it follows the level threshold of l, and sub-loggers
+// taken from it with Named keep the trace.
+func (l *Logger) WithTrace(tc TraceContext) *Logger {
+	child := newLogger(l.name, l, l.fields)
+	child.trace = tc
+	return child
+}
+
+// ForContext returns l with the trace of the logger carried by ctx (see
+// FromContext), or l itself if there is none.
+func (l *Logger) ForContext(ctx context.Context) *Logger {
+	tc, ok := TraceFromContext(ctx)
+	if !ok || tc == l.trace {
+		return l
+	}
+	return l.WithTrace(tc)
+}
+
+func (l *Logger) Name() string { return l.name }
+
+// SetLevel sets the minimum level of messages written through l and the
+// sub-loggers that do not have a threshold of their own.
+func (l *Logger) SetLevel(level Level) {
+	l.level.Store(int32(level))
+}
+
+// ResetLevel makes l inherit the level threshold of its parent again.
+func (l *Logger) ResetLevel() {
+	if l.parent != nil {
+		l.level.Store(levelUnset)
+	}
+}
+
+// Level returns the effective level threshold of l.
+func (l *Logger) Level() Level {
+	for ; l != nil; l = l.parent {
+		if level := l.level.Load(); level != levelUnset {
+			return Level(level)
+		}
+	}
+	return LevelDebug
+}
+
+// Enabled tells if messages of level are written through l. EVENT and FATAL
+// messages always are.
+func (l *Logger) Enabled(level Level) bool {
+	return level == LevelEvent || level >= LevelFatal || level >= l.Level()
+}
+
+// ------------------------------------------------------------
+
+func (l *Logger) tag(m Message) Message {
+	m.logger = l
+	return m
+}
+
+func (l *Logger) DebugMsg(format string, v ...any) Message {
+	return l.tag(DebugMsg(format, v...))
+}
+
+func (l *Logger) ErrorMsg(format string, v ...any) Message {
+	return l.tag(ErrorMsg(format, v...))
+}
+
+func (l *Logger) EventMsg(format string, v ...any) Message {
+	return l.tag(EventMsg(format, v...))
+}
+
+func (l *Logger) InfoMsg(format string, v ...any) Message {
+	return l.tag(InfoMsg(format, v...))
+}
+
+func (l *Logger) WarningMsg(format string, v ...any) Message {
+	return l.tag(WarningMsg(format, v...))
+}
+
+func (l *Logger) FatalMsg(format string, v ...any) Message {
+	return l.tag(FatalMsg(format, v...))
+}
+
+func (l *Logger) ErrMsg(err error) Message {
+	return l.tag(ErrMsg(err))
+}
+
+// ------------------------------------------------------------
+
+func (l *Logger) DEBUG(format string, v ...any) {
+	if !l.Enabled(LevelDebug) {
+		return
+	}
+	l.tag(newMessage(LevelDebug, format, v)).write()
+}
+
+func (l *Logger) ERROR(format string, v ...any) {
+	if !l.Enabled(LevelError) {
+		return
+	}
+	l.tag(newMessage(LevelError, format, v)).write()
+}
+
+func (l *Logger) EVENT(format string, v ...any) {
+	if !l.Enabled(LevelEvent) {
+		return
+	}
+	l.tag(newMessage(LevelEvent, format, v)).write()
+}
+
+func (l *Logger) INFO(format string, v ...any) {
+	if !l.Enabled(LevelInfo) {
+		return
+	}
+	l.tag(newMessage(LevelInfo, format, v)).write()
+}
+
+func (l *Logger) WARNING(format string, v ...any) {
+	if !l.Enabled(LevelWarning) {
+		return
+	}
+	l.tag(newMessage(LevelWarning, format, v)).write()
+}
+
+func (l *Logger) FATAL(format string, v ...any) {
+	l.tag(newMessage(LevelFatal, format, v)).write()
+}
+
+func (l *Logger) ERR(err error) {
+	if !l.Enabled(LevelError) {
+		return
+	}
+	l.ErrMsg(err).write()
+}
@@ -0,0 +1,113 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNamed(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	setForcedTime(time.Unix(42000000, 42000000))
+
+	parent := Named("test")
+	child := parent.Named("child")
+
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		resetForcedTime()
+		parent.ResetLevel()
+		child.ResetLevel()
+	}()
+
+	// Test object
+	parent.SetLevel(LevelWarning)
+	child.INFO("dropped by inherited level")
+	child.With("user", "alice").WithFields(Field{"note", "two words"}, Field{"n", 1}).WARNING("kept")
+	child.SetLevel(LevelDebug)
+	child.DEBUG("kept by own level")
+	parent.INFO("dropped by own level")
+
+	if Named("test.child") != child {
+		t.Errorf("Named did not return the existing logger")
+	}
+
+	// Verify output.
+	const expected = "04:40:00.042 [test.child] " + warningColor + "WARNING: kept" + resetColor +
+		` user=alice note="two words" n=1` + "\n" +
+		"04:40:00.042 [test.child] " + debugColor + "kept by own level" + resetColor + "\n"
+	if out.String() != expected {
+		t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+			expected,
+			out.String())
+	}
+}
+
+func TestNamed_Fields(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	setForcedTime(time.Unix(42000000, 42000000))
+
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		resetForcedTime()
+	}()
+
+	// Test object
+	withFields := Named("probe").With("req", 1).Named("sub")
+	withFields.INFO("first")
+	Named("probe").Named("sub").INFO("second")
+
+	if withFields == Named("probe.sub") {
+		t.Errorf("FAIL: Named shared a logger with fields")
+	}
+
+	// Verify output.
+	const expected = "04:40:00.042 [probe.sub] " + infoColor + "INFO: first" + resetColor + " req=1\n" +
+		"04:40:00.042 [probe.sub] " + infoColor + "INFO: second" + resetColor + "\n"
+	if out.String() != expected {
+		t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+			expected,
+			out.String())
+	}
+}
+
+func TestEventBypassesLevel(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(nil)
+	setForcedTime(time.Unix(42000000, 42000000))
+	remove := AddSink(NewWriterSink(&out, FormatText, false, LevelDebug))
+
+	// Cleanup
+	defer func() {
+		remove()
+		ResetOutput()
+		resetForcedTime()
+		SetLevel(LevelDebug)
+	}()
+
+	// Test object
+	SetLevel(LevelWarning)
+	INFO("dropped")
+	EVENT("sign-in")
+	Named("test").EVENT("sign-out")
+	chain := Chain(EventMsg("GET /"))
+	chain.Add(DebugMsg("200 OK"))
+	chain.Write()
+
+	// Verify output.
+	const expected = "04:40:00.042 EVENT: sign-in\n" +
+		"04:40:00.042 [test] EVENT: sign-out\n" +
+		"04:40:00.042 EVENT: GET / -> 200 OK\n"
+	if out.String() != expected {
+		t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+			expected,
+			out.String())
+	}
+}
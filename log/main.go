@@ -1,9 +1,10 @@
 package log
 
 import (
-	"fmt"
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,86 +21,103 @@ const (
 
 // ------------------------------------------------------------
 
-var mainOutput io.Writer = os.Stderr
+// outputSettings configure the main output. They are replaced as a whole, so
+// that a record is never written with half of a change.
+type outputSettings struct {
+	w      io.Writer
+	format Format
+	color  bool
+}
+
+var mainOutput struct {
+	sync.Mutex // serializes changes
+	settings   atomic.Pointer[outputSettings]
+}
+
 var forcedTime time.Time
 
-func SetOutput(w io.Writer)      { mainOutput = w }
-func ResetOutput()               { mainOutput = os.Stderr }
+func init() {
+	mainOutput.settings.Store(&outputSettings{w: os.Stderr, format: FormatText, color: true})
+}
+
+func changeOutput(change func(s *outputSettings)) {
+	mainOutput.Lock()
+	defer mainOutput.Unlock()
+
+	s := *mainOutput.settings.Load()
+	change(&s)
+	mainOutput.settings.Store(&s)
+}
+
+// SetOutput sets the main output. nil disables it, leaving only the sinks.
+func SetOutput(w io.Writer)      { changeOutput(func(s *outputSettings) { s.w = w }) }
+func ResetOutput()               { SetOutput(os.Stderr) }
 func setForcedTime(ft time.Time) { forcedTime = ft }
 func resetForcedTime()           { forcedTime = time.Time{} }
 
 // ------------------------------------------------------------
 
 func DebugMsg(format string, v ...any) Message {
-	message := fmt.Sprintf(format, v...)
-	return Message{
-		panic: false,
-		Line:  debugColor + message + resetColor,
-	}
+	return deferredMessage(LevelDebug, format, v)
 }
 
 func ErrorMsg(format string, v ...any) Message {
-	message := fmt.Sprintf(format, v...)
-	return Message{
-		panic: false,
-		Line:  errorColor + "ERROR: " + message + resetColor,
-	}
+	return deferredMessage(LevelError, format, v)
 }
 
 func EventMsg(format string, v ...any) Message {
-	message := fmt.Sprintf(format, v...)
-	return Message{
-		panic: false,
-		Line:  eventColor + "EVENT: " + message + resetColor,
-	}
+	return deferredMessage(LevelEvent, format, v)
 }
 
 func InfoMsg(format string, v ...any) Message {
-	message := fmt.Sprintf(format, v...)
-	return Message{
-		panic: false,
-		Line:  infoColor + "INFO: " + message + resetColor,
-	}
+	return deferredMessage(LevelInfo, format, v)
 }
 
 func WarningMsg(format string, v ...any) Message {
-	message := fmt.Sprintf(format, v...)
-	return Message{
-		panic: false,
-		Line:  warningColor + "WARNING: " + message + resetColor,
-	}
+	return deferredMessage(LevelWarning, format, v)
 }
 
 func FatalMsg(format string, v ...any) Message {
-	message := fmt.Sprintf(format, v...)
-	return Message{
-		panic: true,
-		Line:  fatalColor + "FATAL: " + message + resetColor,
-	}
+	return deferredMessage(LevelFatal, format, v)
 }
 
 // ------------------------------------------------------------
 
 func DEBUG(format string, v ...any) {
-	DebugMsg(format, v...).write()
+	if !rootLogger.Enabled(LevelDebug) {
+		return
+	}
+	newMessage(LevelDebug, format, v).write()
 }
 
 func ERROR(format string, v ...any) {
-	ErrorMsg(format, v...).write()
+	if !rootLogger.Enabled(LevelError) {
+		return
+	}
+	newMessage(LevelError, format, v).write()
 }
 
 func EVENT(format string, v ...any) {
-	EventMsg(format, v...).write()
+	if !rootLogger.Enabled(LevelEvent) {
+		return
+	}
+	newMessage(LevelEvent, format, v).write()
 }
 
 func INFO(format string, v ...any) {
-	InfoMsg(format, v...).write()
+	if !rootLogger.Enabled(LevelInfo) {
+		return
+	}
+	newMessage(LevelInfo, format, v).write()
 }
 
 func WARNING(format string, v ...any) {
-	WarningMsg(format, v...).write()
+	if !rootLogger.Enabled(LevelWarning) {
+		return
+	}
+	newMessage(LevelWarning, format, v).write()
 }
 
 func FATAL(format string, v ...any) {
-	FatalMsg(format, v...).write()
+	newMessage(LevelFatal, format, v).write()
 }
@@ -0,0 +1,14 @@
+package log
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestMain pins the local time zone to UTC+2, the offset the times in the
+// expected outputs assume, so that they don't depend on the TZ of the machine.
+func TestMain(m *testing.M) {
+	time.Local = time.FixedZone("", 2*60*60)
+	os.Exit(m.Run())
+}
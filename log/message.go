@@ -1,50 +1,288 @@
 package log
 
 import (
-	"io"
+	"fmt"
 	"time"
 )
 
+const chainSeparator = " -> "
+
+// Message is one log message. The XxxMsg functions keep the format and up to
+// maxDeferredArgs arguments, and format the text only when a record of the
+// message passes the level threshold; arguments pointed to must therefore not
+// be modified before the message is written. The level functions (DEBUG,
+// INFO...) create their message only if it passes the threshold.
 type Message struct {
 	panic bool
-	Line  string
+	// Line is the message as it appears in text output, colored. It is filled
+	// in when the message is written. A Line set before that is written as is,
+	// in place of the line rendered from the message.
+	Line string
+
+	level     Level
+	format    string
+	args      [maxDeferredArgs]any
+	nargs     int
+	formatted bool // text is set, and format and args are not used
+	ownLine   bool // Line was rendered from the message
+	text      string
+	parts     []string
+	causes    []Cause
+	logger    *Logger
 }
 
-func (m Message) write() {
-	// Use forcedTime or the clock.
-	var now time.Time
-	if !forcedTime.IsZero() {
-		now = forcedTime
-	} else {
-		now = time.Now()
+// maxDeferredArgs is the number of arguments a Message keeps for deferred
+// formatting. Messages with more arguments are formatted when created.
+const maxDeferredArgs = 4
+
+// newMessage returns a formatted message, for the level functions that have
+// checked the threshold already.
+func newMessage(level Level, format string, args []any) Message {
+	return Message{
+		panic:     level == LevelFatal,
+		level:     level,
+		formatted: true,
+		text:      limitLength(fmt.Sprintf(format, args...)),
+	}
+}
+
+// deferredMessage returns a message formatted only when it is written. The
+// arguments are copied into the message, so that the caller's argument slice
+// can stay on the stack.
+func deferredMessage(level Level, format string, args []any) Message {
+	if len(args) > maxDeferredArgs {
+		return newMessage(level, format, args)
+	}
+	m := Message{
+		panic:  level == LevelFatal,
+		level:  level,
+		format: format,
+		nargs:  len(args),
 	}
+	copy(m.args[:], args)
+	return m
+}
 
-	line := now.Format("15:04:05.000") + " " + m.Line + "\n"
-	io.WriteString(mainOutput, line)
+func (m Message) Level() Level    { return m.level }
+func (m Message) Causes() []Cause { return m.causes }
 
-	if m.panic {
-		panic(m.Line)
+// Text returns the message text, without level label or colors. A message
+// with neither format nor text, only a Line, returns its Line.
+func (m Message) Text() string {
+	text := m.text
+	if !m.formatted {
+		text = limitLength(fmt.Sprintf(m.format, m.args[:m.nargs]...))
+	}
+	if len(text) == 0 {
+		return m.Line
 	}
+	return text
 }
 
-func LOG(msgList ...Message) {
-	if msgList == nil {
-		ERROR("nil")
-	} else if len(msgList) == 1 {
-		msgList[0].write()
+// Component returns the name of the Logger the message was created with, or
+// an empty string for the package-level functions.
+func (m Message) Component() string {
+	if m.logger == nil {
+		return ""
+	}
+	return m.logger.name
+}
+
+// resolve formats the text of m and fills in Line, once m is known to be
+// written.
+func (m *Message) resolve() {
+	if !m.formatted {
+		m.text = m.Text()
+		m.formatted = true
+		m.format, m.args, m.nargs = "", [maxDeferredArgs]any{}, 0
+	}
+	if len(m.Line) > 0 {
+		return
+	}
+	bufPtr := bufferPool.Get().(*[]byte)
+
+	buf := m.appendLine((*bufPtr)[:0], false, true)
+	m.Line = string(buf)
+	m.ownLine = true
+
+	if cap(buf) <= maxPooledBuffer {
+		*bufPtr = buf
+		bufferPool.Put(bufPtr)
+	}
+}
+
+// appendLine appends the line of m to dst, colored if color is set. With
+// indent, continuation lines of a multi-line text are indented. A Line set by
+// the user is appended as is.
+func (m Message) appendLine(dst []byte, indent bool, color bool) []byte {
+	if len(m.Line) > 0 && !m.ownLine {
+		return append(dst, m.Line...)
+	}
+
+	var start, reset string
+	if color {
+		start, reset = currentTheme.Load()[m.level], resetColor
+	}
+
+	dst = append(dst, start...)
+	if m.level != LevelDebug {
+		dst = append(dst, m.level.String()...)
+		dst = append(dst, ": "...)
+	}
+	if m.parts == nil {
+		dst = appendText(dst, m.Text(), indent)
 	} else {
-		var combined Message
-		for idx, m := range msgList {
+		for idx, part := range m.parts {
 			if idx > 0 {
-				combined.Line += " -> "
+				dst = append(dst, reset...)
+				dst = append(dst, chainSeparator...)
+				dst = append(dst, start...)
 			}
+			dst = appendText(dst, part, indent)
+		}
+	}
+	return append(dst, reset...)
+}
 
-			combined.Line += m.Line
+// write writes m, if it passes the level threshold.
+func (m Message) write() {
+	msgList := [1]Message{m}
+	if !passes(msgList[:]) {
+		return
+	}
+	r := &Record{Time: now()}
+	r.single[0] = m
+	r.Messages = r.single[:]
+	deliver(r)
+}
 
-			if m.panic {
-				combined.panic = true
-			}
+// LOG writes the messages chained into one record, if it passes the level
+// threshold. The messages are copied, so msgList may be reused afterwards.
+func LOG(msgList ...Message) {
+	switch {
+	case msgList == nil:
+		ERROR("nil")
+	case passes(msgList):
+		r := &Record{Time: now()}
+		if len(msgList) == 1 {
+			r.single[0] = msgList[0]
+			r.Messages = r.single[:]
+		} else {
+			r.Messages = append([]Message(nil), msgList...)
+		}
+		deliver(r)
+	}
+}
+
+// ------------------------------------------------------------
+
+// Record is a single log entry: one or more chained messages sharing a
+// timestamp. Trace is set if the record was logged through a Logger with a
+// trace (see WithTrace and FromContext).
+type Record struct {
+	Time     time.Time
+	Messages []Message
+	Trace    TraceContext
+
+	// single holds Messages of a record of one message, saving an allocation.
+	single [1]Message
+}
+
+// Level returns the most severe level among the chained messages.
+func (r *Record) Level() Level {
+	return levelOf(r.Messages)
+}
+
+// Component returns the component of the first message that has one.
+func (r *Record) Component() string {
+	return r.logger().name
+}
+
+// Fields returns the fields of the record's component logger.
+func (r *Record) Fields() []Field {
+	return r.logger().fields
+}
+
+func (r *Record) logger() *Logger {
+	return loggerOf(r.Messages)
+}
+
+func levelOf(msgList []Message) Level {
+	level := LevelDebug
+	for idx := range msgList {
+		if msgList[idx].level > level {
+			level = msgList[idx].level
+		}
+	}
+	return level
+}
+
+func loggerOf(msgList []Message) *Logger {
+	for idx := range msgList {
+		if msgList[idx].logger != nil {
+			return msgList[idx].logger
+		}
+	}
+	return rootLogger
+}
+
+// passes tells if a record of msgList passes the level threshold of its
+// logger: if it chains an EVENT message, or its level is enabled. It is checked
+// before the record is built, so that discarded messages cost no allocations.
+func passes(msgList []Message) bool {
+	return hasEvent(msgList) || loggerOf(msgList).Enabled(levelOf(msgList))
+}
+
+func hasEvent(msgList []Message) bool {
+	for idx := range msgList {
+		if msgList[idx].level == LevelEvent {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver writes r to the outputs and hooks, regardless of level thresholds.
+func deliver(r *Record) {
+	if !r.Trace.IsValid() {
+		r.Trace = r.logger().trace
+	}
+
+	// Format the messages once, now that the record is known to be written.
+	for idx := range r.Messages {
+		r.Messages[idx].resolve()
+	}
+
+	countRecord(r)
+	writeOutput(r)
+	writeSinks(r)
+	runHooks(r)
+
+	for idx := range r.Messages {
+		if r.Messages[idx].panic {
+			panic(joinLines(r.Messages))
+		}
+	}
+}
+
+func now() time.Time {
+	// Use forcedTime or the clock.
+	if !forcedTime.IsZero() {
+		return forcedTime
+	}
+	return time.Now()
+}
+
+func joinLines(msgList []Message) string {
+	return string(appendLines(nil, msgList, false, true))
+}
+
+func appendLines(dst []byte, msgList []Message, indent bool, color bool) []byte {
+	for idx := range msgList {
+		if idx > 0 {
+			dst = append(dst, chainSeparator...)
 		}
-		combined.write()
+		dst = msgList[idx].appendLine(dst, indent, color)
 	}
+	return dst
 }
@@ -0,0 +1,141 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// otelSeverities maps levels to OpenTelemetry severity numbers.
+var otelSeverities = [...]int{
+	LevelDebug:   5,  // DEBUG
+	LevelInfo:    9,  // INFO
+	LevelEvent:   10, // INFO2
+	LevelWarning: 13, // WARN
+	LevelError:   17, // ERROR
+	LevelFatal:   21, // FATAL
+}
+
+type otelKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otelLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 map[string]any `json:"body"`
+	Attributes           []otelKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+	Flags                int            `json:"flags,omitempty"`
+}
+
+// appendOTelRecord renders r as an OpenTelemetry LogRecord in the OTLP/JSON
+// encoding. The component, chain segments, error causes and fields become
+// attributes; causes follow the "exception.*" semantic conventions.
+func appendOTelRecord(dst []byte, r *Record) []byte {
+	texts := make([]string, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		texts = append(texts, m.Text())
+	}
+
+	timestamp := strconv.FormatInt(r.Time.UnixNano(), 10)
+	level := r.Level()
+	out := otelLogRecord{
+		TimeUnixNano:         timestamp,
+		ObservedTimeUnixNano: timestamp,
+		SeverityNumber:       otelSeverities[level],
+		SeverityText:         level.String(),
+		Body:                 otelValue(strings.Join(texts, chainSeparator)),
+	}
+
+	if name := r.Component(); len(name) > 0 {
+		out.Attributes = append(out.Attributes, otelKeyValue{"component", otelValue(name)})
+	}
+	if len(r.Messages) > 1 {
+		chain := make([]string, 0, len(r.Messages))
+		for idx, m := range r.Messages {
+			chain = append(chain, m.level.String()+": "+texts[idx])
+		}
+		out.Attributes = append(out.Attributes, otelKeyValue{"log.chain", otelValue(chain)})
+	}
+
+	var causes []string
+	for _, m := range r.Messages {
+		for _, c := range m.causes {
+			if causes == nil {
+				out.Attributes = append(out.Attributes,
+					otelKeyValue{"exception.type", otelValue(c.Type)},
+					otelKeyValue{"exception.message", otelValue(c.Message)})
+			}
+			causes = append(causes, c.Type+": "+c.Message)
+		}
+	}
+	if len(causes) > 1 {
+		out.Attributes = append(out.Attributes, otelKeyValue{"exception.causes", otelValue(causes)})
+	}
+
+	for _, f := range r.Fields() {
+		out.Attributes = append(out.Attributes, otelKeyValue{f.Key, otelValue(f.Value)})
+	}
+
+	if r.Trace.IsValid() {
+		out.TraceID = r.Trace.TraceIDString()
+		out.SpanID = r.Trace.SpanIDString()
+		out.Flags = int(r.Trace.Flags)
+	}
+
+	// otelValue produces only encodable values, so Marshal cannot fail.
+	data, _ := json.Marshal(&out)
+	dst = append(dst, data...)
+	return append(dst, '\n')
+}
+
+// otelValue wraps value in an OTLP/JSON AnyValue. 64-bit integers are encoded
+// as strings, as the OTLP/JSON mapping requires.
+func otelValue(value any) map[string]any {
+	switch v := value.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int8:
+		return map[string]any{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int16:
+		return map[string]any{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int32:
+		return map[string]any{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case uint8:
+		return map[string]any{"intValue": strconv.FormatUint(uint64(v), 10)}
+	case uint16:
+		return map[string]any{"intValue": strconv.FormatUint(uint64(v), 10)}
+	case uint32:
+		return map[string]any{"intValue": strconv.FormatUint(uint64(v), 10)}
+	case float32:
+		return otelValue(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		return map[string]any{"doubleValue": v}
+	case []string:
+		values := make([]map[string]any, 0, len(v))
+		for _, s := range v {
+			values = append(values, otelValue(s))
+		}
+		return map[string]any{"arrayValue": map[string]any{"values": values}}
+	case error:
+		return map[string]any{"stringValue": v.Error()}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
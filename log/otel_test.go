@@ -0,0 +1,60 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOTel_Format(t *testing.T) {
+	// Test object
+	r := &Record{
+		Time:     time.Unix(42000000, 42000000),
+		Messages: []Message{Named("test").With("attempt", 3).ErrMsg(fmt.Errorf("save: %w", errors.New("disk full")))},
+	}
+	data := appendOTelRecord(nil, r)
+
+	// Verify
+	var got struct {
+		TimeUnixNano   string         `json:"timeUnixNano"`
+		SeverityNumber int            `json:"severityNumber"`
+		SeverityText   string         `json:"severityText"`
+		Body           map[string]any `json:"body"`
+		Attributes     []struct {
+			Key   string         `json:"key"`
+			Value map[string]any `json:"value"`
+		} `json:"attributes"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Invalid JSON %q: %s", data, err)
+	}
+
+	if got.TimeUnixNano != "42000000042000000" || got.SeverityNumber != 17 || got.SeverityText != "ERROR" {
+		t.Errorf("Unexpected header: %s", data)
+	}
+	if got.Body["stringValue"] != "save -> disk full" {
+		t.Errorf("Unexpected body: %v", got.Body)
+	}
+
+	expected := map[string]string{
+		"component":         "test",
+		"exception.type":    "*fmt.wrapError",
+		"exception.message": "save: disk full",
+	}
+	for _, kv := range got.Attributes {
+		if want, ok := expected[kv.Key]; ok {
+			if kv.Value["stringValue"] != want {
+				t.Errorf("%s: got %v, want %s", kv.Key, kv.Value, want)
+			}
+			delete(expected, kv.Key)
+		}
+		if kv.Key == "attempt" && kv.Value["intValue"] != "3" {
+			t.Errorf("attempt: got %v", kv.Value)
+		}
+	}
+	if len(expected) > 0 {
+		t.Errorf("Missing attributes: %v", expected)
+	}
+}
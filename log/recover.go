@@ -0,0 +1,105 @@
+package log
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+)
+
+// Recover logs a panic of the calling goroutine as ERROR, with the stack
+// trace, and stops the panic. It must be deferred directly:
+//
+//	defer log.Recover("mailer")
+func Recover(label string) {
+	if err := recover(); err != nil {
+		logPanic(label, err)
+	}
+}
+
+func logPanic(label string, err any) {
+	ERROR("Goroutine '%s' panicked: %v\n%s", label, err, debug.Stack())
+}
+
+// Go runs fn in a new goroutine that logs and recovers panics like Recover.
+// The returned channel is closed when fn has returned.
+func Go(label string, fn func()) <-chan struct{} {
+	return GoRestart(context.Background(), label, RestartPolicy{}, func(context.Context) { fn() })
+}
+
+// UnlimitedRestarts is the RestartPolicy.MaxRestarts of a worker restarted
+// after every panic.
+const UnlimitedRestarts = -1
+
+// RestartPolicy tells GoRestart how to restart a worker that panicked.
+type RestartPolicy struct {
+	// Delay before the first restart; it doubles after every consecutive
+	// panic, up to MaxBackoff. Defaults are 100ms and 1 minute.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// MaxRestarts limits the number of restarts; zero means no restarts, and
+	// UnlimitedRestarts (or any negative value) no limit.
+	MaxRestarts int
+}
+
+// GoRestart is like Go, but restarts fn according to policy whenever it
+// panics. The worker stops for good when fn returns normally, or when ctx
+// ends: no restart is made after that, and fn is given ctx to stop early.
+func GoRestart(ctx context.Context, label string, policy RestartPolicy, fn func(ctx context.Context)) <-chan struct{} {
+	if policy.MinBackoff <= 0 {
+		policy.MinBackoff = 100 * time.Millisecond
+	}
+	if policy.MaxBackoff < policy.MinBackoff {
+		policy.MaxBackoff = time.Minute
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		backoff := policy.MinBackoff
+		for restarts := 0; ; restarts++ {
+			started := time.Now()
+			if !runRecovered(ctx, label, fn) || ctx.Err() != nil {
+				return
+			}
+			if policy.MaxRestarts >= 0 && restarts >= policy.MaxRestarts {
+				return
+			}
+
+			// A worker that ran for a good while starts over with a short delay.
+			if time.Since(started) > policy.MaxBackoff {
+				backoff = policy.MinBackoff
+			}
+			WARNING("Restarting goroutine '%s' in %s", label, backoff)
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+
+			if backoff *= 2; backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+	}()
+
+	return done
+}
+
+// runRecovered calls fn and reports whether it panicked.
+func runRecovered(ctx context.Context, label string, fn func(context.Context)) (panicked bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			logPanic(label, err)
+			panicked = true
+		}
+	}()
+
+	fn(ctx)
+	return false
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGo_Panic(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	defer ResetOutput()
+
+	// Test object
+	var runs int
+	done := Go("worker", func() {
+		runs++
+		panic("boom")
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Goroutine did not finish")
+	}
+
+	// Verify output.
+	if !strings.Contains(out.String(), "ERROR: Goroutine 'worker' panicked: boom\n") ||
+		!strings.Contains(out.String(), "recover_test.go") {
+		t.Errorf("Output lacks panic report:\n%s", out.String())
+	}
+	if runs != 1 {
+		t.Errorf("Worker ran %d times, want 1", runs)
+	}
+}
+
+func TestGoRestart(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	defer ResetOutput()
+
+	// Test object: panics twice, then returns normally.
+	var runs int
+	policy := RestartPolicy{MinBackoff: time.Millisecond, MaxRestarts: UnlimitedRestarts}
+	done := GoRestart(context.Background(), "worker", policy, func(context.Context) {
+		if runs++; runs < 3 {
+			panic("boom")
+		}
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Goroutine did not finish")
+	}
+
+	// Verify
+	if runs != 3 {
+		t.Errorf("Worker ran %d times, want 3", runs)
+	}
+	if n := strings.Count(out.String(), "WARNING: Restarting goroutine 'worker'"); n != 2 {
+		t.Errorf("Expected 2 restarts in output, got %d:\n%s", n, out.String())
+	}
+}
+
+func TestGoRestart_Cancel(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	defer ResetOutput()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{}, 1)
+
+	// Test object: panics at once, and would be restarted an hour later.
+	policy := RestartPolicy{MinBackoff: time.Hour, MaxRestarts: UnlimitedRestarts}
+	done := GoRestart(ctx, "worker", policy, func(context.Context) {
+		started <- struct{}{}
+		panic("boom")
+	})
+	<-started
+	cancel()
+
+	// Verify
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Canceled goroutine did not finish")
+	}
+}
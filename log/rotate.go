@@ -0,0 +1,142 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// RotatingFile is an append-only file that is rotated once it grows past
+// MaxSize bytes: "app.log" is renamed to "app.log.1", "app.log.1" to
+// "app.log.2" and so on, keeping at most MaxFiles old files.
+//
+// If a rotation fails, writing continues to the current file and the
+// rotation is retried on the next write.
+type RotatingFile struct {
+	mutex    sync.Mutex
+	fileName string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+	reopen   bool // the file was moved aside, but a new one is not open yet
+}
+
+func NewRotatingFile(fileName string, maxSize int64, maxFiles int) (*RotatingFile, error) {
+	rf := &RotatingFile{
+		fileName: fileName,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// Write appends p to the file, rotating first if p would not fit. A single
+// write is never split between files. If the rotation fails, p is written
+// anyway and the error of the rotation returned.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mutex.Lock()
+	defer rf.mutex.Unlock()
+
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	var rotateErr error
+	if rf.reopen || (rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize) {
+		rotateErr = rf.rotate()
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+func (rf *RotatingFile) Close() error {
+	rf.mutex.Lock()
+	defer rf.mutex.Unlock()
+
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.fileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	rf.file = file
+	rf.size = info.Size()
+	return nil
+}
+
+// rotate moves the file aside and opens a new one. The old file is closed only
+// once the new one is open.
+func (rf *RotatingFile) rotate() error {
+	if !rf.reopen {
+		if rf.maxFiles > 0 {
+			if err := rf.makeRoom(); err != nil {
+				return err
+			}
+			if err := os.Rename(rf.fileName, rf.backupName(1)); err != nil {
+				return err
+			}
+		} else if err := os.Remove(rf.fileName); err != nil {
+			return err
+		}
+		rf.reopen = true
+	}
+
+	old := rf.file
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.reopen = false
+	return old.Close()
+}
+
+// makeRoom frees the name of backup 1 by moving the backups up to the first
+// free name, dropping the oldest one if there is none.
+func (rf *RotatingFile) makeRoom() error {
+	free := 1
+	for ; free <= rf.maxFiles; free++ {
+		if _, err := os.Lstat(rf.backupName(free)); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+	}
+	if free > rf.maxFiles {
+		free = rf.maxFiles
+		if err := os.Remove(rf.backupName(free)); err != nil {
+			return err
+		}
+	}
+
+	for idx := free - 1; idx >= 1; idx-- {
+		if err := os.Rename(rf.backupName(idx), rf.backupName(idx+1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rf *RotatingFile) backupName(idx int) string {
+	return fmt.Sprintf("%s.%d", rf.fileName, idx)
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile_Retry(t *testing.T) {
+	// Setup
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "app.log")
+	rf, err := NewRotatingFile(fileName, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A non-empty directory in place of the backup keeps it from being
+	// removed.
+	blocker := filepath.Join(fileName+".1", "blocker")
+	if err := os.MkdirAll(blocker, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cleanup
+	defer rf.Close()
+
+	// Test object
+	rf.Write([]byte("0123456789"))
+	n, err := rf.Write([]byte("abc"))
+	if n != 3 || err == nil {
+		t.Errorf("FAIL: failed rotation: wrote %d bytes, error %v", n, err)
+	}
+
+	if err := os.RemoveAll(fileName + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rf.Write([]byte("def")); err != nil {
+		t.Errorf("FAIL: retried rotation: %v", err)
+	}
+
+	// Verify output.
+	var data = []struct {
+		fileName string
+		content  string
+	}{
+		{fileName, "def"},
+		{fileName + ".1", "0123456789abc"},
+	}
+
+	for i := range data {
+		content, err := os.ReadFile(data[i].fileName)
+		if err != nil || string(content) != data[i].content {
+			t.Errorf("FAIL: %s contains %q (%v), want %q",
+				data[i].fileName,
+				content,
+				err,
+				data[i].content)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+//go:build unix
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleLevelSignals lets the global level threshold be changed at runtime:
+// SIGUSR1 makes logging one level more verbose and SIGUSR2 one level less
+// verbose, between DEBUG and ERROR. EVENT is skipped, as EVENT messages are
+// always written and an EVENT threshold filters like WARNING. The returned
+// function stops the handling.
+func HandleLevelSignals() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				level := GetLevel()
+				switch {
+				case sig == syscall.SIGUSR1 && level > LevelDebug:
+					ChangeLevel("", stepLevel(level, -1), "SIGUSR1")
+				case sig == syscall.SIGUSR2 && level < LevelError:
+					ChangeLevel("", stepLevel(level, +1), "SIGUSR2")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
+// stepLevel returns the level step levels away from level, skipping EVENT.
+func stepLevel(level Level, step Level) Level {
+	level += step
+	if level == LevelEvent {
+		level += step
+	}
+	return level
+}
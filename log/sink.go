@@ -0,0 +1,99 @@
+package log
+
+import (
+	"io"
+	"sync"
+)
+
+// Sink is an additional destination for log records. Every record that
+// passes the level threshold is written to the main output and then to every
+// registered sink. Write must not block for long; sinks that do I/O should
+// queue the record and return.
+type Sink interface {
+	Write(r *Record)
+	Close() error
+}
+
+var sinks struct {
+	sync.RWMutex
+	list []Sink
+}
+
+// AddSink registers a sink and returns a function that removes it again. The
+// sink is not closed on removal.
+func AddSink(s Sink) (remove func()) {
+	sinks.Lock()
+	defer sinks.Unlock()
+
+	// Copy-on-write, so that writeSinks can iterate without holding the lock.
+	list := make([]Sink, len(sinks.list), len(sinks.list)+1)
+	copy(list, sinks.list)
+	sinks.list = append(list, s)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { removeSink(s) })
+	}
+}
+
+func removeSink(s Sink) {
+	sinks.Lock()
+	defer sinks.Unlock()
+
+	list := make([]Sink, 0, len(sinks.list))
+	for _, e := range sinks.list {
+		if e != s {
+			list = append(list, e)
+		}
+	}
+	sinks.list = list
+}
+
+func writeSinks(r *Record) {
+	sinks.RLock()
+	list := sinks.list
+	sinks.RUnlock()
+
+	for _, s := range list {
+		s.Write(r)
+	}
+}
+
+// ------------------------------------------------------------
+
+// WriterSink writes records at or above a level to an io.Writer in the given
+// format. Close closes the writer if it is an io.Closer.
+type WriterSink struct {
+	mutex  sync.Mutex
+	w      io.Writer
+	format Format
+	color  bool
+	level  Level
+}
+
+func NewWriterSink(w io.Writer, format Format, color bool, level Level) *WriterSink {
+	return &WriterSink{
+		w:      w,
+		format: format,
+		color:  color,
+		level:  level,
+	}
+}
+
+func (sink *WriterSink) Write(r *Record) {
+	if r.Level() < sink.level {
+		return
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+
+	writeFormatted(sink.w, sink.format, sink.color, r)
+}
+
+func (sink *WriterSink) Close() error {
+	if closer, ok := sink.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Framing selects how TCPSink separates records on the wire.
+type Framing int
+
+const (
+	// FramingJSONLines sends one JSON object per line.
+	FramingJSONLines Framing = iota
+	// FramingLengthPrefixed sends each JSON object after its length as a
+	// 4-byte big-endian integer.
+	FramingLengthPrefixed
+)
+
+type TCPSinkConfig struct {
+	Address string
+	Framing Framing
+
+	// SpoolFile buffers records while the collector is unreachable. Records
+	// left in it by an earlier run are sent first.
+	SpoolFile string
+	// MaxSpool limits the size of the spool file in bytes; records that would
+	// grow it further are dropped. The default is 64 MiB.
+	MaxSpool int64
+
+	// Reconnect backoff; defaults are 100ms doubling up to 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// TCPSink streams records to a collector over TCP. While the connection is
+// down, records are appended to a spool file and replayed in order once the
+// connection is back.
+//
+// A broken connection is noticed when the collector closes its end or when a
+// write fails, so records written in the moment the collector goes away can
+// still be lost.
+type TCPSink struct {
+	cfg     TCPSinkConfig
+	queue   chan []byte
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	// ctx is canceled by Close, to abort dialing.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex  sync.RWMutex
+	closed bool
+
+	// Owned by the worker goroutine
+	conn      net.Conn
+	connLost  chan struct{}
+	spool     *os.File
+	spoolSize int64
+}
+
+const (
+	tcpSinkQueueSize    = 1024
+	tcpSinkWriteTimeout = 10 * time.Second
+	tcpSinkMaxSpool     = 64 << 20
+)
+
+func NewTCPSink(cfg TCPSinkConfig) (*TCPSink, error) {
+	if len(cfg.SpoolFile) == 0 {
+		return nil, errors.New("log.NewTCPSink: SpoolFile is required")
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.MaxSpool <= 0 {
+		cfg.MaxSpool = tcpSinkMaxSpool
+	}
+
+	spool, err := os.OpenFile(cfg.SpoolFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := spool.Stat()
+	if err != nil {
+		spool.Close()
+		return nil, err
+	}
+
+	sink := &TCPSink{
+		cfg:       cfg,
+		queue:     make(chan []byte, tcpSinkQueueSize),
+		done:      make(chan struct{}),
+		spool:     spool,
+		spoolSize: info.Size(),
+	}
+	sink.ctx, sink.cancel = context.WithCancel(context.Background())
+	go sink.worker()
+	return sink, nil
+}
+
+// Write queues r for sending. If the queue is full the record is dropped and
+// counted in Dropped.
+func (sink *TCPSink) Write(r *Record) {
+	frame := sink.frame(r)
+
+	sink.mutex.RLock()
+	defer sink.mutex.RUnlock()
+
+	if sink.closed {
+		return
+	}
+
+	select {
+	case sink.queue <- frame:
+	default:
+		sink.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of records dropped because the queue or the
+// spool file was full, or the spool file could not be written.
+func (sink *TCPSink) Dropped() uint64 {
+	return sink.dropped.Load()
+}
+
+// Close stops accepting records, sends or spools the queued ones and closes
+// the connection and the spool file. A connection attempt in progress is
+// aborted.
+func (sink *TCPSink) Close() error {
+	sink.mutex.Lock()
+	if sink.closed {
+		sink.mutex.Unlock()
+		return nil
+	}
+	sink.closed = true
+	close(sink.queue)
+	sink.mutex.Unlock()
+
+	sink.cancel()
+	<-sink.done
+	return nil
+}
+
+func (sink *TCPSink) frame(r *Record) []byte {
+	switch sink.cfg.Framing {
+	case FramingLengthPrefixed:
+		frame := appendJSONRecord(make([]byte, 4, 256), r)
+		frame = frame[:len(frame)-1] // no trailing newline
+		binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+		return frame
+	default:
+		return appendJSONRecord(nil, r)
+	}
+}
+
+// ------------------------------------------------------------
+
+func (sink *TCPSink) worker() {
+	defer close(sink.done)
+	defer func() { sink.spool.Close() }() // cutSpool replaces the file
+
+	backoff := sink.cfg.MinBackoff
+	for {
+		if sink.conn == nil {
+			if err := sink.connect(); err != nil {
+				if !sink.spoolFor(backoff) {
+					return
+				}
+				if backoff *= 2; backoff > sink.cfg.MaxBackoff {
+					backoff = sink.cfg.MaxBackoff
+				}
+				continue
+			}
+			backoff = sink.cfg.MinBackoff
+		}
+
+		select {
+		case frame, ok := <-sink.queue:
+			if !ok {
+				sink.conn.Close()
+				return
+			}
+			if err := sink.send(frame); err != nil {
+				sink.disconnect()
+				sink.spoolFrame(frame)
+			}
+		case <-sink.connLost:
+			sink.disconnect()
+		}
+	}
+}
+
+// connect dials the collector and replays the spool file.
+func (sink *TCPSink) connect() error {
+	ctx, cancel := context.WithTimeout(sink.ctx, tcpSinkWriteTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", sink.cfg.Address)
+	if err != nil {
+		return err
+	}
+	sink.conn = conn
+	sink.connLost = make(chan struct{})
+	go watchConn(conn, sink.connLost)
+
+	if err := sink.replay(); err != nil {
+		sink.disconnect()
+		return err
+	}
+	return nil
+}
+
+// replay sends the spooled records one by one. The records that were sent are
+// cut from the spool file even if the connection fails midway, so that they
+// are not sent again.
+func (sink *TCPSink) replay() error {
+	if sink.spoolSize == 0 {
+		return nil
+	}
+	if _, err := sink.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	in := bufio.NewReader(io.LimitReader(sink.spool, sink.spoolSize))
+	var sent int64
+	var err error
+	for sent < sink.spoolSize {
+		frame, readErr := sink.readFrame(in, sink.spoolSize-sent)
+		if readErr != nil {
+			// A record cut short by a crash; nothing after it can be framed.
+			sink.dropped.Add(1)
+			sent = sink.spoolSize
+			break
+		}
+		if err = sink.send(frame); err != nil {
+			break
+		}
+		sent += int64(len(frame))
+	}
+
+	if cutErr := sink.cutSpool(sent); err == nil {
+		err = cutErr
+	}
+	return err
+}
+
+// readFrame reads one record, as framed by frame, from the spool file. left is
+// the number of bytes left in the spool file; a length prefix that points past
+// it is corrupt, like a record cut short.
+func (sink *TCPSink) readFrame(in *bufio.Reader, left int64) ([]byte, error) {
+	switch sink.cfg.Framing {
+	case FramingLengthPrefixed:
+		frame := make([]byte, 4)
+		if _, err := io.ReadFull(in, frame); err != nil {
+			return nil, err
+		}
+		size := binary.BigEndian.Uint32(frame)
+		if int64(size) > left-4 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		frame = append(frame, make([]byte, size)...)
+		if _, err := io.ReadFull(in, frame[4:]); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	default:
+		return in.ReadBytes('\n')
+	}
+}
+
+// spoolFrame appends frame to the spool file. The frame is dropped if the
+// spool file is full or can't be written.
+func (sink *TCPSink) spoolFrame(frame []byte) {
+	if sink.spoolSize+int64(len(frame)) > sink.cfg.MaxSpool {
+		sink.dropped.Add(1)
+		return
+	}
+	if _, err := sink.spool.Write(frame); err != nil {
+		// Don't leave part of the frame behind.
+		sink.spool.Truncate(sink.spoolSize)
+		sink.dropped.Add(1)
+		return
+	}
+	sink.spoolSize += int64(len(frame))
+}
+
+// cutSpool removes the first n bytes of the spool file. Unless that is all of
+// it, the rest is copied to a new file that replaces the spool file.
+func (sink *TCPSink) cutSpool(n int64) error {
+	switch {
+	case n == 0:
+		return nil
+	case n >= sink.spoolSize:
+		if err := sink.spool.Truncate(0); err != nil {
+			return err
+		}
+		sink.spoolSize = 0
+		return nil
+	}
+
+	fileName := sink.cfg.SpoolFile
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, io.NewSectionReader(sink.spool, n, sink.spoolSize-n))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), fileName); err != nil {
+		return err
+	}
+
+	spool, err := os.OpenFile(fileName, os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	sink.spool.Close()
+	sink.spool = spool
+	sink.spoolSize -= n
+	return nil
+}
+
+// watchConn closes lost when the connection is closed by either end. Anything
+// the collector sends is ignored.
+func watchConn(conn net.Conn, lost chan struct{}) {
+	defer close(lost)
+
+	var buf [256]byte
+	for {
+		if _, err := conn.Read(buf[:]); err != nil {
+			return
+		}
+	}
+}
+
+func (sink *TCPSink) disconnect() {
+	sink.conn.Close()
+	sink.conn = nil
+}
+
+func (sink *TCPSink) send(frame []byte) error {
+	// Writes to a connection closed by the peer may still succeed, so check
+	// the watcher first.
+	select {
+	case <-sink.connLost:
+		return net.ErrClosed
+	default:
+	}
+
+	sink.conn.SetWriteDeadline(time.Now().Add(tcpSinkWriteTimeout))
+	_, err := sink.conn.Write(frame)
+	return err
+}
+
+// spoolFor moves queued records to the spool file for the duration of the
+// backoff. It returns false if the sink was closed meanwhile.
+func (sink *TCPSink) spoolFor(backoff time.Duration) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	for {
+		select {
+		case frame, ok := <-sink.queue:
+			if !ok {
+				return false
+			}
+			sink.spoolFrame(frame)
+		case <-timer.C:
+			return true
+		}
+	}
+}
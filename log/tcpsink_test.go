@@ -0,0 +1,232 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTCPSink_Reconnect(t *testing.T) {
+	// Setup
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+
+	sink, err := NewTCPSink(TCPSinkConfig{
+		Address:    address,
+		SpoolFile:  filepath.Join(t.TempDir(), "spool"),
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetOutput(io.Discard)
+	remove := AddSink(sink)
+
+	// Cleanup
+	defer func() {
+		remove()
+		sink.Close()
+		ResetOutput()
+	}()
+
+	// Test object: first collector receives one record and goes away.
+	INFO("one")
+
+	conn := acceptConn(t, listener)
+	lines := bufio.NewScanner(conn)
+	expectMessage(t, lines, "one")
+	conn.Close()
+	listener.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	// Records logged while the collector is down are spooled...
+	INFO("two")
+	INFO("three")
+	time.Sleep(100 * time.Millisecond)
+
+	// ... and replayed in order once it is back.
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	conn = acceptConn(t, listener)
+	defer conn.Close()
+	lines = bufio.NewScanner(conn)
+	expectMessage(t, lines, "two")
+	expectMessage(t, lines, "three")
+
+	INFO("four")
+	expectMessage(t, lines, "four")
+}
+
+func TestTCPSink_LengthPrefixed(t *testing.T) {
+	// Setup
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	sink, err := NewTCPSink(TCPSinkConfig{
+		Address:   listener.Addr().String(),
+		Framing:   FramingLengthPrefixed,
+		SpoolFile: filepath.Join(t.TempDir(), "spool"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	// Test object
+	sink.Write(&Record{Time: time.Now(), Messages: []Message{InfoMsg("framed")}})
+
+	// Verify
+	conn := acceptConn(t, listener)
+	defer conn.Close()
+
+	var size uint32
+	if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(payload, &got); err != nil || got.Message != "framed" {
+		t.Errorf("Unexpected frame %q (%v)", payload, err)
+	}
+}
+
+func TestTCPSink_MaxSpool(t *testing.T) {
+	// Setup: nothing listens at the address.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	spoolFile := filepath.Join(t.TempDir(), "spool")
+	sink, err := NewTCPSink(TCPSinkConfig{
+		Address:   address,
+		SpoolFile: spoolFile,
+		MaxSpool:  1024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Test object
+	for i := 0; i < 50; i++ {
+		sink.Write(&Record{Time: time.Now(), Messages: []Message{InfoMsg("message %d", i)}})
+	}
+	start := time.Now()
+	sink.Close()
+
+	// Verify output.
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("FAIL: Close took %s", elapsed)
+	}
+	info, err := os.Stat(spoolFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() == 0 || info.Size() > 1024 || sink.Dropped() == 0 {
+		t.Errorf("FAIL: spool of %d bytes, %d dropped", info.Size(), sink.Dropped())
+	}
+}
+
+func TestTCPSink_CutSpool(t *testing.T) {
+	// Setup
+	spoolFile := filepath.Join(t.TempDir(), "spool")
+	const frames = "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n"
+	if err := os.WriteFile(spoolFile, []byte(frames), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spool, err := os.OpenFile(spoolFile, os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &TCPSink{
+		cfg:       TCPSinkConfig{SpoolFile: spoolFile, MaxSpool: tcpSinkMaxSpool},
+		spool:     spool,
+		spoolSize: int64(len(frames)),
+	}
+
+	// Cleanup
+	defer func() { sink.spool.Close() }()
+
+	// Test object: the first record was sent before the connection failed.
+	if err := sink.cutSpool(8); err != nil {
+		t.Fatal(err)
+	}
+	sink.spoolFrame([]byte("{\"n\":4}\n"))
+
+	// Verify output.
+	const expected = "{\"n\":2}\n{\"n\":3}\n{\"n\":4}\n"
+	data, err := os.ReadFile(spoolFile)
+	if err != nil || string(data) != expected || sink.spoolSize != int64(len(expected)) {
+		t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+			expected,
+			data)
+	}
+}
+
+func TestTCPSink_CorruptLength(t *testing.T) {
+	// Setup: a length prefix far beyond the end of the spool file.
+	frames := append(binary.BigEndian.AppendUint32(nil, 0xfffffff0), "{}"...)
+	sink := &TCPSink{cfg: TCPSinkConfig{Framing: FramingLengthPrefixed}}
+
+	// Test object
+	frame, err := sink.readFrame(bufio.NewReader(bytes.NewReader(frames)), int64(len(frames)))
+
+	// Verify output.
+	if err == nil {
+		t.Errorf("FAIL: corrupt frame %q was read", frame)
+	}
+}
+
+// ------------------------------------------------------------
+
+func acceptConn(t *testing.T, listener net.Listener) net.Conn {
+	t.Helper()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func expectMessage(t *testing.T, lines *bufio.Scanner, message string) {
+	t.Helper()
+
+	if !lines.Scan() {
+		t.Fatalf("Expected %q, got %v", message, lines.Err())
+	}
+	var got struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(lines.Bytes(), &got); err != nil || got.Message != message {
+		t.Errorf("Expected %q, got %q (%v)", message, lines.Text(), err)
+	}
+}
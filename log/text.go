@@ -0,0 +1,61 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+var maxMessageLength atomic.Int64
+
+// SetMaxMessageLength limits the length of message texts to max bytes. Longer
+// texts are cut and end with a marker telling how much was dropped. Zero
+// (the default) means no limit.
+func SetMaxMessageLength(max int) { maxMessageLength.Store(int64(max)) }
+func ResetMaxMessageLength()      { maxMessageLength.Store(0) }
+
+func limitLength(text string) string {
+	max := int(maxMessageLength.Load())
+	if max <= 0 || len(text) <= max {
+		return text
+	}
+
+	// Don't cut a multi-byte character in half.
+	cut := max
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+
+	return fmt.Sprintf("%s…[truncated %d bytes]", text[:cut], len(text)-cut)
+}
+
+// ------------------------------------------------------------
+
+// textIndent lines up continuation lines with the message text, past the
+// "15:04:05.000 " timestamp.
+const textIndent = "             "
+
+// appendText appends text to dst. With indent, a multi-line text is kept in
+// one record of text output by indenting every line after the first.
+func appendText(dst []byte, text string, indent bool) []byte {
+	if !indent {
+		return append(dst, text...)
+	}
+
+	for len(text) > 0 {
+		idx := strings.IndexAny(text, "\r\n")
+		if idx < 0 {
+			return append(dst, text...)
+		}
+		dst = append(dst, text[:idx]...)
+		dst = append(dst, '\n')
+		dst = append(dst, textIndent...)
+
+		if strings.HasPrefix(text[idx:], "\r\n") {
+			idx++
+		}
+		text = text[idx+1:]
+	}
+	return dst
+}
@@ -0,0 +1,122 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMultiLine_Text(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	setForcedTime(time.Unix(42000000, 42000000))
+
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		resetForcedTime()
+	}()
+
+	// Test object
+	INFO("first\nsecond\r\nthird")
+
+	// Verify output.
+	const expected = "04:40:00.042 " + infoColor + "INFO: first\n" +
+		"             second\n" +
+		"             third" + resetColor + "\n"
+	if out.String() != expected {
+		t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+			expected,
+			out.String())
+	}
+}
+
+func TestMultiLine_JSON(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	SetFormat(FormatJSON)
+
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		ResetFormat()
+	}()
+
+	// Test object
+	INFO("first\nsecond")
+
+	// Verify output.
+	if strings.Count(out.String(), "\n") != 1 {
+		t.Fatalf("Record is not on a single line: %q", out.String())
+	}
+	var got struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &got); err != nil || got.Message != "first\nsecond" {
+		t.Errorf("Message did not survive encoding: %q (%v)", got.Message, err)
+	}
+}
+
+func Test_LimitLength(t *testing.T) {
+	// Setup
+	SetMaxMessageLength(8)
+
+	// Cleanup
+	defer ResetMaxMessageLength()
+
+	var data = []struct {
+		input string
+		want  string
+	}{
+		{
+			input: "short",
+			want:  "short",
+		}, {
+			input: "exactly8",
+			want:  "exactly8",
+		}, {
+			input: "0123456789",
+			want:  "01234567…[truncated 2 bytes]",
+		}, {
+			input: "1234567äö",
+			want:  "1234567…[truncated 4 bytes]",
+		},
+	}
+
+	for i := range data {
+		if output := limitLength(data[i].input); output != data[i].want {
+			t.Errorf("FAIL: %q -> %q != %q", data[i].input, output, data[i].want)
+		}
+	}
+}
+
+func TestMessageLine(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	setForcedTime(time.Unix(42000000, 42000000))
+
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		resetForcedTime()
+	}()
+
+	// Test object
+	LOG(Message{Line: "custom line"})
+	edited := InfoMsg("original")
+	edited.Line = "edited"
+	LOG(edited, WarningMsg("%d ms", 12))
+
+	// Verify output.
+	const expected = "04:40:00.042 custom line\n" +
+		"04:40:00.042 edited -> " + warningColor + "WARNING: 12 ms" + resetColor + "\n"
+	if out.String() != expected {
+		t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+			expected,
+			out.String())
+	}
+}
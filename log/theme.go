@@ -0,0 +1,92 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Theme holds the ANSI escape sequence that starts the color of each level
+// in FormatText output.
+type Theme [LevelFatal + 1]string
+
+var (
+	// DefaultTheme uses the 16 basic colors and suits dark terminals.
+	DefaultTheme = Theme{
+		LevelDebug:   debugColor,
+		LevelInfo:    infoColor,
+		LevelEvent:   eventColor,
+		LevelWarning: warningColor,
+		LevelError:   errorColor,
+		LevelFatal:   fatalColor,
+	}
+
+	// LightTheme uses the 16 basic colors and suits light terminals.
+	LightTheme = Theme{
+		LevelDebug:   "\x1B[90m",
+		LevelInfo:    "\x1B[39m",
+		LevelEvent:   "\x1B[34m",
+		LevelWarning: "\x1B[33m",
+		LevelError:   "\x1B[31m",
+		LevelFatal:   "\x1B[97;41m",
+	}
+
+	// Theme256 uses the xterm 256-color palette.
+	Theme256 = Theme{
+		LevelDebug:   Color256(244),
+		LevelInfo:    Color256(255),
+		LevelEvent:   Color256(75),
+		LevelWarning: Color256(214),
+		LevelError:   Color256(203),
+		LevelFatal:   Color256(231) + "\x1B[48;5;160m",
+	}
+
+	// TrueColorTheme uses 24-bit colors.
+	TrueColorTheme = Theme{
+		LevelDebug:   TrueColor(0x80, 0x80, 0x80),
+		LevelInfo:    TrueColor(0xf0, 0xf0, 0xf0),
+		LevelEvent:   TrueColor(0x5f, 0xaf, 0xff),
+		LevelWarning: TrueColor(0xff, 0xaf, 0x00),
+		LevelError:   TrueColor(0xff, 0x5f, 0x5f),
+		LevelFatal:   TrueColor(0xff, 0xff, 0xff) + "\x1B[48;2;204;0;0m",
+	}
+)
+
+// Color256 returns the escape sequence for foreground color n of the xterm
+// 256-color palette.
+func Color256(n uint8) string {
+	return fmt.Sprintf("\x1B[38;5;%dm", n)
+}
+
+// TrueColor returns the escape sequence for a 24-bit foreground color.
+func TrueColor(r, g, b uint8) string {
+	return fmt.Sprintf("\x1B[38;2;%d;%d;%dm", r, g, b)
+}
+
+var currentTheme atomic.Pointer[Theme]
+
+func init() {
+	ResetTheme()
+}
+
+// SetTheme sets the colors of FormatText output, for the main output and the
+// sinks alike.
+func SetTheme(t Theme) { currentTheme.Store(&t) }
+func ResetTheme()      { SetTheme(DefaultTheme) }
+
+// ParseTheme returns the theme with the given name: "default", "light",
+// "256" or "truecolor".
+func ParseTheme(name string) (Theme, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "default":
+		return DefaultTheme, nil
+	case "light":
+		return LightTheme, nil
+	case "256":
+		return Theme256, nil
+	case "truecolor":
+		return TrueColorTheme, nil
+	default:
+		return DefaultTheme, fmt.Errorf("log.ParseTheme: unknown theme '%s'", name)
+	}
+}
@@ -0,0 +1,58 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTheme(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	SetTheme(Theme256)
+	setForcedTime(time.Unix(42000000, 42000000))
+
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		ResetTheme()
+		resetForcedTime()
+	}()
+
+	// Test object
+	WARNING("careful")
+
+	// Verify output.
+	const expected = "04:40:00.042 \x1B[38;5;214mWARNING: careful" + resetColor + "\n"
+	if out.String() != expected {
+		t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+			expected,
+			out.String())
+	}
+}
+
+func TestAppendHTML(t *testing.T) {
+	data := []struct {
+		input    string
+		expected string
+	}{
+		{"plain <b> & text", "plain &lt;b&gt; &amp; text"},
+		{errorColor + "ERROR: x" + resetColor + "\n", `<span class="ansi-fg-9">ERROR: x</span>` + "\n"},
+		{fatalColor + "F", `<span class="ansi-bg-1">F</span>`},
+		{"\x1B[1;31mA\x1B[22mB\x1B[0mC", `<span class="ansi-bold ansi-fg-1">A</span><span class="ansi-fg-1">B</span>C`},
+		{Color256(214) + "W" + resetColor, `<span style="color: #ffaf00">W</span>`},
+		{Color256(3) + "W" + resetColor, `<span class="ansi-fg-3">W</span>`},
+		{TrueColor(1, 2, 3) + "\x1B[48;5;232mT", `<span style="color: #010203; background-color: #080808">T</span>`},
+		{"a\x1B[2Kb\x1B(Bc", "ab(Bc"},
+	}
+
+	for _, d := range data {
+		got := string(AppendHTML(nil, []byte(d.input)))
+		if got != d.expected {
+			t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+				d.expected,
+				got)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"time"
+)
+
+// Timed starts timing an operation. The returned function logs
+// "<name> took <elapsed>" at DEBUG level when called:
+//
+//	defer log.Timed("load sessions")()
+func Timed(name string) (done func()) {
+	return rootLogger.TimedLevel(LevelDebug, 0, name)
+}
+
+// TimedLevel is like Timed, but logs at level, or at WARNING if the operation
+// took longer than warnAfter (if warnAfter is positive).
+func TimedLevel(level Level, warnAfter time.Duration, name string) (done func()) {
+	return rootLogger.TimedLevel(level, warnAfter, name)
+}
+
+func (l *Logger) Timed(name string) (done func()) {
+	return l.TimedLevel(LevelDebug, 0, name)
+}
+
+func (l *Logger) TimedLevel(level Level, warnAfter time.Duration, name string) (done func()) {
+	start := time.Now()
+	return func() {
+		l.tag(timedMsg(level, warnAfter, name, time.Since(start))).write()
+	}
+}
+
+func timedMsg(level Level, warnAfter time.Duration, name string, elapsed time.Duration) Message {
+	elapsed = elapsed.Round(time.Microsecond)
+	if warnAfter > 0 && elapsed > warnAfter {
+		return WarningMsg("%s took %s (limit %s)", name, elapsed, warnAfter)
+	}
+	return newMessage(level, "%s took %s", []any{name, elapsed})
+}
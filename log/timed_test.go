@@ -0,0 +1,43 @@
+package log
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimed(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+	SetColor(false)
+
+	// Cleanup
+	defer func() {
+		ResetOutput()
+		ResetColor()
+	}()
+
+	// Test object
+	Timed("quick")()
+
+	slow := TimedLevel(LevelInfo, time.Millisecond, "slow")
+	time.Sleep(2 * time.Millisecond)
+	slow()
+
+	chain := Chain(EventMsg("GET /"))
+	step := chain.Timed("render", time.Hour)
+	step()
+	chain.Write()
+
+	// Verify output.
+	expected := regexp.MustCompile(`^\S+ quick took \S+s\n` +
+		`\S+ WARNING: slow took \S+s \(limit 1ms\)\n` +
+		`\S+ EVENT: GET / -> render took \S+s\n$`)
+	if !expected.MatchString(out.String()) {
+		t.Errorf("Output does not match expected:\nWANT:\n%s\nGOT:\n%s",
+			expected,
+			out.String())
+	}
+}
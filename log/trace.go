@@ -0,0 +1,127 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// TraceContext identifies a span of a distributed trace, as carried by the
+// W3C Trace Context headers "traceparent" and "tracestate".
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+	State   string
+}
+
+// NewTraceContext starts a new trace, with random trace and span ids.
+func NewTraceContext() TraceContext {
+	var tc TraceContext
+	rand.Read(tc.TraceID[:])
+	rand.Read(tc.SpanID[:])
+	return tc
+}
+
+// ParseTraceparent parses a "traceparent" header value. It returns false if
+// the value is not valid.
+func ParseTraceparent(header string) (TraceContext, bool) {
+	var tc TraceContext
+
+	// version "-" trace-id "-" parent-id "-" trace-flags
+	header = strings.TrimSpace(header)
+	if len(header) < 55 || (len(header) > 55 && header[55] != '-') {
+		return tc, false
+	}
+	if header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return tc, false
+	}
+
+	version, ok := parseLowerHex(header[0:2])
+	if !ok || version[0] == 0xff || (version[0] == 0 && len(header) != 55) {
+		return tc, false
+	}
+	traceID, ok := parseLowerHex(header[3:35])
+	if !ok || isZero(traceID) {
+		return tc, false
+	}
+	spanID, ok := parseLowerHex(header[36:52])
+	if !ok || isZero(spanID) {
+		return tc, false
+	}
+	flags, ok := parseLowerHex(header[53:55])
+	if !ok {
+		return tc, false
+	}
+
+	copy(tc.TraceID[:], traceID)
+	copy(tc.SpanID[:], spanID)
+	tc.Flags = flags[0]
+	return tc, true
+}
+
+// Child returns a new span in the same trace.
+func (tc TraceContext) Child() TraceContext {
+	child := tc
+	rand.Read(child.SpanID[:])
+	return child
+}
+
+func (tc TraceContext) IsValid() bool {
+	return !isZero(tc.TraceID[:]) && !isZero(tc.SpanID[:])
+}
+
+// Traceparent returns tc as a "traceparent" header value.
+func (tc TraceContext) Traceparent() string {
+	return "00-" + tc.TraceIDString() + "-" + tc.SpanIDString() + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+func (tc TraceContext) TraceIDString() string { return hex.EncodeToString(tc.TraceID[:]) }
+func (tc TraceContext) SpanIDString() string  { return hex.EncodeToString(tc.SpanID[:]) }
+
+func parseLowerHex(s string) ([]byte, bool) {
+	if strings.ToLower(s) != s {
+		return nil, false
+	}
+	data, err := hex.DecodeString(s)
+	return data, err == nil
+}
+
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ------------------------------------------------------------
+
+type loggerKey struct{}
+
+// WithContext returns a copy of ctx that carries l.
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the root logger, which
+// the package-level functions use.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		return l
+	}
+	return rootLogger
+}
+
+// ContextWithTrace returns a copy of ctx whose logger (see FromContext)
+// attaches tc to every record.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return WithContext(ctx, FromContext(ctx).WithTrace(tc))
+}
+
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc := FromContext(ctx).trace
+	return tc, tc.IsValid()
+}
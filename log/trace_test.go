@@ -0,0 +1,84 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func Test_ParseTraceparent(t *testing.T) {
+	var data = []struct {
+		input string
+		valid bool
+	}{
+		{
+			input: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			valid: true,
+		}, {
+			input: "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future",
+			valid: true, // Unknown versions may append fields
+		}, {
+			input: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future",
+			valid: false,
+		}, {
+			input: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			valid: false,
+		}, {
+			input: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			valid: false,
+		}, {
+			input: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			valid: false,
+		}, {
+			input: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			valid: false, // Upper case is not allowed
+		}, {
+			input: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			valid: false,
+		}, {
+			input: "",
+			valid: false,
+		},
+	}
+
+	for i := range data {
+		tc, ok := ParseTraceparent(data[i].input)
+		if ok != data[i].valid {
+			t.Errorf("FAIL: %q -> %v, want %v", data[i].input, ok, data[i].valid)
+		}
+		if ok && !strings.HasPrefix(data[i].input, "cc") && tc.Traceparent() != data[i].input {
+			t.Errorf("FAIL: %q -> %q", data[i].input, tc.Traceparent())
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	// Setup
+	var out strings.Builder
+	SetOutput(&out)
+
+	tc, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithTrace(context.Background(), tc)
+
+	// Cleanup
+	defer ResetOutput()
+
+	// Test object
+	FromContext(ctx).INFO("traced")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Named("test").ForContext(ctx).With("id", 7).INFO("traced in another goroutine")
+	}()
+	<-done
+	INFO("untraced")
+	FromContext(context.Background()).INFO("untraced")
+
+	// Verify output.
+	lines := strings.Split(out.String(), "\n")
+	const traceFields = " trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7"
+	if !strings.HasSuffix(lines[0], traceFields) || !strings.HasSuffix(lines[1], traceFields) ||
+		strings.Contains(lines[2], "trace_id") || strings.Contains(lines[3], "trace_id") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+}